@@ -1,41 +1,262 @@
 package apis
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var errNotFound = errors.New("not found")
 
-func get(url, username, token string) ([]byte, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// RateLimitError is returned when API rate limit is exceeded and the reset
+// time is too far away to wait for it.
+type RateLimitError struct {
+	URL   string
+	Reset time.Time // zero if unknown
+	hint  string
+}
+
+func (e *RateLimitError) Error() string {
+	msg := "API rate limit exceeded"
+	if !e.Reset.IsZero() {
+		msg += fmt.Sprintf(", resets at %s", e.Reset.Local().Format(time.RFC1123))
+	}
+	if e.hint != "" {
+		msg += ". " + e.hint
+	}
+	return msg
+}
+
+const (
+	// maxRetries is the number of retries of transient errors
+	maxRetries = 3
+	// retryDelay is the initial delay between retries, doubled after each one
+	retryDelay = time.Second
+	// maxRateLimitWaits is the number of waits for rate limit reset
+	maxRateLimitWaits = 3
+	// secondaryRateLimitWait is the delay after hitting Github secondary rate
+	// limit without Retry-After header
+	secondaryRateLimitWait = time.Minute
+)
+
+var (
+	// timeNow and sleep are replaced in tests.
+	timeNow = time.Now
+	sleep   = sleepContext
+)
+
+// sleepContext pauses for duration d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Get returns the response body for url without using cache or credentials.
+func (c *Client) Get(ctx context.Context, url string) ([]byte, error) {
+	return c.fetch(ctx, url, "", "")
+}
+
+// get returns the response body for url, using the on-disk cache if possible.
+// Cached responses expire after c.CacheTTL.
+func (c *Client) get(ctx context.Context, url, username, token string) ([]byte, error) {
+	if e, ok := c.cacheRead(url); ok {
+		return e.result()
+	}
+
+	body, err := c.fetch(ctx, url, username, token)
+	switch err {
+	case nil:
+		c.cacheWrite(url, body, false, false)
+	case errNotFound:
+		c.cacheWrite(url, nil, true, false)
+	}
+	return body, err
+}
+
+// getCommit returns full commit hash parsed by parse from the response body
+// for url. Only the hash is cached and, since it can't change, it never
+// expires. Not found responses always expire, commits may be pushed later.
+func (c *Client) getCommit(ctx context.Context, url, username, token string, parse func([]byte) (string, error)) (string, error) {
+	if e, ok := c.cacheRead(url); ok {
+		body, err := e.result()
+		if err != nil {
+			return "", err
+		}
+		if isCommitHash(string(body)) {
+			return string(body), nil
+		}
+	}
+
+	body, err := c.fetch(ctx, url, username, token)
+	if err != nil {
+		if err == errNotFound {
+			c.cacheWrite(url, nil, true, false)
+		}
+		return "", err
+	}
+	hash, err := parse(body)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	if isCommitHash(hash) {
+		c.cacheWrite(url, []byte(hash), false, true)
+	}
+	return hash, nil
+}
+
+var commitHashRe = regexp.MustCompile(`\A[0-9a-f]{40}\z`)
+
+// isCommitHash reports whether s is a full SHA-1 commit hash.
+func isCommitHash(s string) bool {
+	return commitHashRe.MatchString(s)
+}
+
+// fetch performs GET request, retrying transient server errors and waiting
+// for rate limit reset as long as the total wait is within c.RateLimitWait.
+func (c *Client) fetch(ctx context.Context, url, username, token string) ([]byte, error) {
+	var (
+		delay       = retryDelay
+		retries     int
+		rateLimited int
+		waited      time.Duration
+	)
+	for {
+		body, wait, err := c.fetchOnce(ctx, url, username, token)
+		if wait == 0 {
+			return body, err
+		}
+
+		var rle *RateLimitError
+		if errors.As(err, &rle) {
+			if rateLimited >= maxRateLimitWaits || waited+wait > c.RateLimitWait {
+				return nil, err
+			}
+			rateLimited++
+			waited += wait
+			if c.Notices != nil {
+				c.Notices.Printf("%v, waiting %v\n", err, wait.Round(time.Second))
+			}
+		} else {
+			if retries >= maxRetries {
+				return nil, err
+			}
+			retries++
+			if wait < delay {
+				wait = delay
+			}
+			delay *= 2
+			c.logf("[apis.fetch] %v, retrying in %v\n", err, wait)
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// fetchOnce performs a single GET request, limited by c.RequestTimeout.
+// Token is sent using basic authentication along with username, or as
+// a bearer token if there's no username.
+// Non-zero wait is returned along with the error if the request can be retried
+// after that delay.
+func (c *Client) fetchOnce(ctx context.Context, url, username, token string) ([]byte, time.Duration, error) {
+	if c.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.RequestTimeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	if username != "" && token != "" {
 		req.SetBasicAuth(username, token)
+	} else if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.httpClient().Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("apis.get %s: %v", url, err)
+		return nil, 0, fmt.Errorf("apis.get %s: %w", url, err)
 	}
 	defer resp.Body.Close()
 
-	switch resp.StatusCode {
-	case http.StatusOK:
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("apis.get %s: %v", url, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("apis.get %s: %v", url, err)
+	}
+
+	switch code := resp.StatusCode; {
+	case code == http.StatusOK:
+		return body, 0, nil
+	case code == http.StatusNotFound:
+		return nil, 0, errNotFound
+	case code == http.StatusForbidden || code == http.StatusTooManyRequests:
+		if wait, ok := retryAfter(resp.Header); ok {
+			// secondary rate limit
+			wait = maxDuration(wait, time.Second)
+			return nil, wait, &RateLimitError{URL: url, Reset: timeNow().Add(wait)}
+		}
+		if strings.Contains(string(body), "secondary rate limit") {
+			// Github secondary rate limit without Retry-After
+			wait := secondaryRateLimitWait
+			return nil, wait, &RateLimitError{URL: url, Reset: timeNow().Add(wait)}
+		}
+		if rateLimitHeader(resp.Header, "Remaining") == "0" || strings.Contains(string(body), "rate limit exceeded") {
+			rle := &RateLimitError{URL: url}
+			if reset, err := strconv.ParseInt(rateLimitHeader(resp.Header, "Reset"), 10, 64); err == nil {
+				rle.Reset = time.Unix(reset, 0)
+				return nil, maxDuration(rle.Reset.Sub(timeNow()), time.Second), rle
+			}
+			return nil, 0, rle
 		}
-		return body, nil
-	case http.StatusNotFound:
-		return nil, errNotFound
-	default:
-		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("apis.get %s: %d, body: %v", url, resp.StatusCode, string(body))
+	case code >= 500:
+		wait, _ := retryAfter(resp.Header)
+		return nil, maxDuration(wait, time.Nanosecond), fmt.Errorf("apis.get %s: %d, body: %v", url, code, string(body))
+	}
+	return nil, 0, fmt.Errorf("apis.get %s: %d, body: %v", url, resp.StatusCode, string(body))
+}
+
+// rateLimitHeader returns X-RateLimit-<name> (Github) or RateLimit-<name> (Gitlab) header value.
+func rateLimitHeader(h http.Header, name string) string {
+	if v := h.Get("X-RateLimit-" + name); v != "" {
+		return v
+	}
+	return h.Get("RateLimit-" + name)
+}
+
+// retryAfter parses Retry-After header, which is either a number of seconds or HTTP date.
+func retryAfter(h http.Header) (time.Duration, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+		return time.Duration(n) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return maxDuration(t.Sub(timeNow()), 0), true
+	}
+	return 0, false
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
 	}
+	return b
 }
@@ -0,0 +1,176 @@
+package apis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFetchRetry(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var slept []time.Duration
+
+	oldNow, oldSleep := timeNow, sleep
+	defer func() {
+		timeNow, sleep = oldNow, oldSleep
+	}()
+	timeNow = func() time.Time { return now }
+	sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+
+	examples := []struct {
+		// responses returned one after another, the last one is repeated
+		responses []func(w http.ResponseWriter)
+		body      string
+		slept     []time.Duration
+		rateLimit bool
+		reset     time.Time
+	}{
+		// rate limit resets in 10 minutes
+		{
+			[]func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("X-RateLimit-Remaining", "0")
+					w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(10*time.Minute).Unix(), 10))
+					w.WriteHeader(http.StatusForbidden)
+					w.Write([]byte("API rate limit exceeded"))
+				},
+				func(w http.ResponseWriter) { w.Write([]byte("ok")) },
+			},
+			"ok", []time.Duration{10 * time.Minute}, false, time.Time{},
+		},
+		// rate limit resets too late
+		{
+			[]func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("X-RateLimit-Remaining", "0")
+					w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(2*time.Hour).Unix(), 10))
+					w.WriteHeader(http.StatusForbidden)
+				},
+			},
+			"", nil, true, now.Add(2 * time.Hour),
+		},
+		// secondary rate limit
+		{
+			[]func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("Retry-After", "60")
+					w.WriteHeader(http.StatusForbidden)
+				},
+				func(w http.ResponseWriter) { w.Write([]byte("ok")) },
+			},
+			"ok", []time.Duration{time.Minute}, false, time.Time{},
+		},
+		// secondary rate limit without Retry-After
+		{
+			[]func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.WriteHeader(http.StatusForbidden)
+					w.Write([]byte(`{"message": "You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`))
+				},
+				func(w http.ResponseWriter) { w.Write([]byte("ok")) },
+			},
+			"ok", []time.Duration{time.Minute}, false, time.Time{},
+		},
+		// rate limit keeps resetting shortly
+		{
+			[]func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("X-RateLimit-Remaining", "0")
+					w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(time.Second).Unix(), 10))
+					w.WriteHeader(http.StatusForbidden)
+				},
+			},
+			"", []time.Duration{time.Second, time.Second, time.Second}, true, now.Add(time.Second),
+		},
+		// secondary rate limit exceeding total wait
+		{
+			[]func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("Retry-After", "1800")
+					w.WriteHeader(http.StatusForbidden)
+				},
+			},
+			"", []time.Duration{30 * time.Minute, 30 * time.Minute}, true, now.Add(30 * time.Minute),
+		},
+		// transient server error
+		{
+			[]func(w http.ResponseWriter){
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) },
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) },
+				func(w http.ResponseWriter) { w.Write([]byte("ok")) },
+			},
+			"ok", []time.Duration{time.Second, 2 * time.Second}, false, time.Time{},
+		},
+		// persistent server error
+		{
+			[]func(w http.ResponseWriter){
+				func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+			},
+			"", []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, false, time.Time{},
+		},
+	}
+
+	for i, x := range examples {
+		var n int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			x.responses[n](w)
+			if n < len(x.responses)-1 {
+				n++
+			}
+		}))
+		slept = nil
+
+		c := &Client{RateLimitWait: time.Hour}
+		body, err := c.fetch(context.Background(), srv.URL, "", "")
+		srv.Close()
+
+		if string(body) != x.body {
+			t.Errorf("(%d) expected body %q, got %q", i, x.body, string(body))
+		}
+		if x.body != "" && err != nil {
+			t.Errorf("(%d) expected no error, got %v", i, err)
+		}
+		if x.body == "" && err == nil {
+			t.Errorf("(%d) expected error", i)
+		}
+		var rle *RateLimitError
+		if errors.As(err, &rle) != x.rateLimit {
+			t.Errorf("(%d) expected RateLimitError to be %v, got %v", i, x.rateLimit, err)
+		}
+		if rle != nil && !rle.Reset.Equal(x.reset) {
+			t.Errorf("(%d) expected reset time %v, got %v", i, x.reset, rle.Reset)
+		}
+		if len(slept) != len(x.slept) {
+			t.Errorf("(%d) expected sleeps %v, got %v", i, x.slept, slept)
+			continue
+		}
+		for j := range slept {
+			if slept[j] != x.slept[j] {
+				t.Errorf("(%d) expected sleeps %v, got %v", i, x.slept, slept)
+				break
+			}
+		}
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	c := &Client{RequestTimeout: 10 * time.Millisecond}
+	_, err := c.fetch(context.Background(), srv.URL, "", "")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error %v, got %v", context.DeadlineExceeded, err)
+	}
+}
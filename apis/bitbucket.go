@@ -0,0 +1,34 @@
+package apis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+type BitbucketCommit struct {
+	Hash string `json:"hash"`
+}
+
+func (c *Client) BitbucketGetCommit(ctx context.Context, account, project, ref string) (string, error) {
+	url := fmt.Sprintf("%s/repositories/%s/%s/commit/%s", c.bitbucketURL(), url.PathEscape(account), url.PathEscape(project), url.PathEscape(ref))
+
+	hash, err := c.getCommit(ctx, url, "", "", func(resp []byte) (string, error) {
+		var res BitbucketCommit
+		if err := json.Unmarshal(resp, &res); err != nil {
+			return "", fmt.Errorf("error unmarshalling: %v, resp: %v", err, string(resp))
+		}
+		return res.Hash, nil
+	})
+	if err != nil {
+		var rle *RateLimitError
+		if errors.As(err, &rle) {
+			return "", rle
+		}
+		return "", fmt.Errorf("error getting commit %s for %s/%s: %v", ref, account, project, err)
+	}
+
+	return hash, nil
+}
@@ -0,0 +1,149 @@
+package apis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// cacheEntry is a cached API response.
+type cacheEntry struct {
+	URL       string    `json:"url"`
+	NotFound  bool      `json:"not_found,omitempty"`
+	Body      []byte    `json:"body,omitempty"`
+	Time      time.Time `json:"time"`
+	Immutable bool      `json:"immutable,omitempty"`
+}
+
+func (e *cacheEntry) result() ([]byte, error) {
+	if e.NotFound {
+		return nil, errNotFound
+	}
+	return e.Body, nil
+}
+
+var (
+	// cache entries are stored as key[:2]/key, key is hex encoded SHA-256 of URL
+	cacheKeyRe    = regexp.MustCompile(`\A[0-9a-f]{64}\z`)
+	cacheSubdirRe = regexp.MustCompile(`\A[0-9a-f]{2}\z`)
+	// cacheTempPrefix is the name prefix of entries being written
+	cacheTempPrefix = ".tmp-"
+)
+
+func (c *Client) cachePath(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(c.CacheDir, key[:2], key)
+}
+
+// cacheRead returns cached response for url, if it exists and hasn't expired yet.
+func (c *Client) cacheRead(url string) (*cacheEntry, bool) {
+	if c.CacheDir == "" {
+		return nil, false
+	}
+
+	b, err := os.ReadFile(c.cachePath(url))
+	if err != nil {
+		return nil, false
+	}
+
+	var e cacheEntry
+	if err := json.Unmarshal(b, &e); err != nil || e.URL != url {
+		return nil, false
+	}
+	if !e.Immutable && timeNow().Sub(e.Time) > c.CacheTTL {
+		return nil, false
+	}
+
+	c.logf("[apis.cacheRead] using cached response for %s\n", url)
+	return &e, true
+}
+
+// cacheWrite stores response for url. Immutable entries never expire.
+func (c *Client) cacheWrite(url string, body []byte, notFound, immutable bool) {
+	if c.CacheDir == "" {
+		return
+	}
+
+	b, err := json.Marshal(&cacheEntry{
+		URL:       url,
+		NotFound:  notFound,
+		Body:      body,
+		Time:      timeNow(),
+		Immutable: immutable,
+	})
+	if err != nil {
+		return
+	}
+
+	// write to a temp file first to avoid concurrent readers seeing partial entries
+	path := c.cachePath(url)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		c.logf("[apis.cacheWrite] %v\n", err)
+		return
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), cacheTempPrefix)
+	if err != nil {
+		c.logf("[apis.cacheWrite] %v\n", err)
+		return
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		c.logf("[apis.cacheWrite] %v\n", err)
+	}
+}
+
+// ClearCache removes all cached API responses. Only cache entries and
+// temporary files are removed, anything else in c.CacheDir is left alone.
+func (c *Client) ClearCache() error {
+	if c.CacheDir == "" {
+		return nil
+	}
+
+	subdirs, err := os.ReadDir(c.CacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, d := range subdirs {
+		if !d.IsDir() || !cacheSubdirRe.MatchString(d.Name()) {
+			continue
+		}
+		dir := filepath.Join(c.CacheDir, d.Name())
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			name := f.Name()
+			if !f.Type().IsRegular() {
+				continue
+			}
+			if (cacheKeyRe.MatchString(name) && strings.HasPrefix(name, d.Name())) || strings.HasPrefix(name, cacheTempPrefix) {
+				if err := os.Remove(filepath.Join(dir, name)); err != nil {
+					return err
+				}
+			}
+		}
+		// keep the subdirectory if there's anything else left in it
+		os.Remove(dir)
+	}
+	// same for the cache directory itself
+	os.Remove(c.CacheDir)
+
+	return nil
+}
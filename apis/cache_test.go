@@ -0,0 +1,152 @@
+package apis
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testCommit = "0123456789abcdef0123456789abcdef01234567"
+
+func TestCache(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/commit":
+			w.Write([]byte(`{"sha":"` + testCommit + `"}`))
+		default:
+			w.Write([]byte(r.URL.Path))
+		}
+	}))
+	defer srv.Close()
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	oldNow := timeNow
+	defer func() {
+		timeNow = oldNow
+	}()
+	timeNow = func() time.Time { return now }
+
+	c := &Client{CacheDir: t.TempDir(), CacheTTL: time.Hour}
+
+	parseCommit := func(resp []byte) (string, error) {
+		var res GithubCommit
+		err := json.Unmarshal(resp, &res)
+		return res.SHA, err
+	}
+
+	examples := []struct {
+		path    string
+		commit  bool
+		advance time.Duration
+		body    string
+		err     error
+		hits    int
+	}{
+		{"/commit", true, 0, testCommit, nil, 1},
+		{"/commit", true, 2 * time.Hour, testCommit, nil, 1},
+		{"/tags", false, 0, "/tags", nil, 2},
+		{"/tags", false, 30 * time.Minute, "/tags", nil, 2},
+		{"/tags", false, 2 * time.Hour, "/tags", nil, 3},
+		{"/missing", false, 0, "", errNotFound, 4},
+		{"/missing", false, 0, "", errNotFound, 4},
+		// not found immutable responses expire too
+		{"/missing?commit", true, 0, "", errNotFound, 5},
+		{"/missing?commit", true, 30 * time.Minute, "", errNotFound, 5},
+		{"/missing?commit", true, 2 * time.Hour, "", errNotFound, 6},
+	}
+
+	for i, x := range examples {
+		now = now.Add(x.advance)
+		var (
+			body []byte
+			err  error
+		)
+		if x.commit {
+			var sha string
+			sha, err = c.getCommit(context.Background(), srv.URL+x.path, "", "", parseCommit)
+			body = []byte(sha)
+		} else {
+			body, err = c.get(context.Background(), srv.URL+x.path, "", "")
+		}
+		if err != x.err {
+			t.Errorf("(%d) expected error %v, got %v", i, x.err, err)
+		}
+		if string(body) != x.body {
+			t.Errorf("(%d) expected body %q, got %q", i, x.body, string(body))
+		}
+		if hits != x.hits {
+			t.Errorf("(%d) expected %d requests, got %d", i, x.hits, hits)
+		}
+	}
+
+	// only the commit hash is cached
+	b, err := os.ReadFile(c.cachePath(srv.URL + "/commit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(b, &e); err != nil {
+		t.Fatal(err)
+	}
+	if string(e.Body) != testCommit {
+		t.Errorf("expected cached commit %q, got %q", testCommit, string(e.Body))
+	}
+
+	if err := c.ClearCache(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.getCommit(context.Background(), srv.URL+"/commit", "", "", parseCommit); err != nil {
+		t.Fatal(err)
+	}
+	if hits != 7 {
+		t.Errorf("expected cleared cache to be bypassed, got %d requests", hits)
+	}
+}
+
+func TestClearCacheKeepsOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	c := &Client{CacheDir: dir, CacheTTL: time.Hour}
+
+	c.cacheWrite("https://example.com/a", []byte("a"), false, false)
+	c.cacheWrite("https://example.com/b", []byte("b"), false, true)
+	entry := c.cachePath("https://example.com/a")
+	temp := filepath.Join(filepath.Dir(entry), cacheTempPrefix+"123")
+
+	other := []string{
+		filepath.Join(dir, "notes.txt"),
+		filepath.Join(dir, "src", "main.go"),
+		filepath.Join(filepath.Dir(entry), "notes.txt"),
+	}
+	for _, p := range append(other, temp) {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.ClearCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, p := range []string{entry, c.cachePath("https://example.com/b"), temp} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("(%d) expected %s to be removed, got %v", i, p, err)
+		}
+	}
+	for i, p := range other {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("(%d) expected %s to be kept, got %v", i, p, err)
+		}
+	}
+}
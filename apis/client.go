@@ -0,0 +1,131 @@
+package apis
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dmgk/modules2tuple/v2/config"
+	"github.com/dmgk/modules2tuple/v2/debug"
+)
+
+const (
+	// DefaultGithubURL is the public Github API base URL.
+	DefaultGithubURL = "https://api.github.com"
+	// DefaultGitlabURL is the public Gitlab site URL.
+	DefaultGitlabURL = "https://gitlab.com"
+	// DefaultSourcehutURL is the public SourceHut git site URL.
+	DefaultSourcehutURL = "https://git.sr.ht"
+	// DefaultGiteaURL is the Codeberg site URL.
+	DefaultGiteaURL = "https://codeberg.org"
+	// DefaultBitbucketURL is the Bitbucket Cloud API URL.
+	DefaultBitbucketURL = "https://api.bitbucket.org/2.0"
+)
+
+// Client calls Github, Gitlab, SourceHut, Gitea and Bitbucket APIs. Zero value
+// is a usable client without credentials, cache, timeouts or rate limit waits.
+type Client struct {
+	// HTTPClient is used to make requests, http.DefaultClient if nil.
+	HTTPClient *http.Client
+	// GithubURL is Github API base URL, e.g. "https://github.example.com/api/v3"
+	// for Github Enterprise. DefaultGithubURL if empty.
+	GithubURL string
+	// GitlabURL is the Gitlab site used when tuple doesn't specify one.
+	// DefaultGitlabURL if empty.
+	GitlabURL string
+	// BitbucketURL is Bitbucket API base URL, DefaultBitbucketURL if empty.
+	BitbucketURL string
+	// GithubUsername and GithubToken are Github API basic auth credentials.
+	GithubUsername string
+	GithubToken    string
+	// SourcehutToken is git.sr.ht OAuth token, sent as bearer token.
+	SourcehutToken string
+
+	// CacheDir is API responses cache directory, cache is disabled if empty.
+	CacheDir string
+	// CacheTTL is how long mutable cached responses (tags, contents) are valid.
+	CacheTTL time.Duration
+	// RequestTimeout limits a single request duration, if non-zero.
+	RequestTimeout time.Duration
+	// RateLimitWait is the longest total time to wait for API rate limit resets
+	// during a single request.
+	RateLimitWait time.Duration
+
+	// Logger receives debug messages, if set.
+	Logger debug.Logger
+	// Notices receives rate limit wait notices, if set.
+	Notices debug.Logger
+}
+
+// NewClient returns Client configured from the command line settings.
+func NewClient() *Client {
+	c := &Client{
+		GithubURL:      config.GithubURL,
+		GitlabURL:      config.GitlabURL,
+		GithubUsername: config.GithubUsername,
+		GithubToken:    config.GithubToken,
+		SourcehutToken: config.SourcehutToken,
+		CacheTTL:       config.CacheTTL,
+		RequestTimeout: config.RequestTimeout,
+		RateLimitWait:  config.RateLimitWait,
+		Notices:        debug.Stderr,
+	}
+	if !config.NoCache {
+		c.CacheDir = config.CacheDir
+	}
+	if config.Debug {
+		c.Logger = debug.Stderr
+	}
+	return c
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) githubURL() string {
+	if c.GithubURL != "" {
+		return strings.TrimSuffix(c.GithubURL, "/")
+	}
+	return DefaultGithubURL
+}
+
+func (c *Client) gitlabURL(site string) string {
+	if site == "" {
+		site = c.GitlabURL
+	}
+	if site == "" {
+		site = DefaultGitlabURL
+	}
+	return strings.TrimSuffix(site, "/")
+}
+
+func (c *Client) sourcehutURL(site string) string {
+	if site == "" {
+		site = DefaultSourcehutURL
+	}
+	return strings.TrimSuffix(site, "/")
+}
+
+func (c *Client) giteaURL(site string) string {
+	if site == "" {
+		site = DefaultGiteaURL
+	}
+	return strings.TrimSuffix(site, "/")
+}
+
+func (c *Client) bitbucketURL() string {
+	if c.BitbucketURL == "" {
+		return DefaultBitbucketURL
+	}
+	return strings.TrimSuffix(c.BitbucketURL, "/")
+}
+
+func (c *Client) logf(format string, v ...interface{}) {
+	if c.Logger != nil {
+		c.Logger.Printf(format, v...)
+	}
+}
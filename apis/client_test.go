@@ -0,0 +1,125 @@
+package apis
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/account/project/git/refs/tags/v1.2.3", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/api/v3/repos/account/project/git/refs/tags/api", func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "token" {
+			t.Errorf("expected basic auth credentials, got %q %q", user, pass)
+		}
+		w.Write([]byte(`[{"ref": "refs/tags/api/v1.2.3"}, {"ref": "refs/tags/api/v1.2.4"}]`))
+	})
+	mux.HandleFunc("/api/v4/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/account%2Fproject/repository/commits/0123456789ab" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"id": "0123456789abcdef0123456789abcdef01234567"}`))
+	})
+	mux.HandleFunc("/api/~account/repos/project/log/v1.2.3", func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer srht-token" {
+			t.Errorf("expected SourceHut bearer token, got %q", auth)
+		}
+		w.Write([]byte(`{"results": [{"id": "89abcdef0123456789abcdef0123456789abcdef"}]}`))
+	})
+	mux.HandleFunc("/api/v1/repos/account/project/tags/v1.2.3", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/api/v1/repos/account/project/tags/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v1/repos/account/project/tags/api%2Fv1.2.3" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"name": "api/v1.2.3"}`))
+	})
+	mux.HandleFunc("/api/v1/repos/account/project/git/commits/0123456789ab", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"sha": "0123456789abcdef0123456789abcdef01234567"}`))
+	})
+	mux.HandleFunc("/2.0/repositories/account/project/commit/v1.2.3", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hash": "fedcba9876543210fedcba9876543210fedcba98"}`))
+	})
+	mux.HandleFunc("/api/v3/repos/account/project/tags", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "1" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`[{"name": "v1.2.4", "commit": {"sha": "aaaaaaaaaaaa0123456789abcdef0123456789ab"}}, {"name": "v1.2.3", "commit": {"sha": "0123456789abcdef0123456789abcdef01234567"}}]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := &Client{
+		HTTPClient:     srv.Client(),
+		GithubURL:      srv.URL + "/api/v3/",
+		GitlabURL:      srv.URL,
+		BitbucketURL:   srv.URL + "/2.0",
+		GithubUsername: "user",
+		GithubToken:    "token",
+		SourcehutToken: "srht-token",
+	}
+
+	tag, err := c.GithubLookupTag(context.Background(), "account", "project", "api", "v1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag != "api/v1.2.3" {
+		t.Errorf("expected tag %q, got %q", "api/v1.2.3", tag)
+	}
+
+	tags, err := c.GithubListTagsAt(context.Background(), "account", "project", "0123456789ab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0] != "v1.2.3" {
+		t.Errorf("expected tags %q, got %q", []string{"v1.2.3"}, tags)
+	}
+
+	hash, err := c.GitlabGetCommit(context.Background(), "", "account", "project", "0123456789ab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != "0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("expected commit %q, got %q", "0123456789abcdef0123456789abcdef01234567", hash)
+	}
+
+	tag, err = c.GiteaLookupTag(context.Background(), srv.URL, "account", "project", "api", "v1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag != "api/v1.2.3" {
+		t.Errorf("expected tag %q, got %q", "api/v1.2.3", tag)
+	}
+
+	hash, err = c.GiteaGetCommit(context.Background(), srv.URL, "account", "project", "0123456789ab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != "0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("expected commit %q, got %q", "0123456789abcdef0123456789abcdef01234567", hash)
+	}
+
+	hash, err = c.BitbucketGetCommit(context.Background(), "account", "project", "v1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != "fedcba9876543210fedcba9876543210fedcba98" {
+		t.Errorf("expected commit %q, got %q", "fedcba9876543210fedcba9876543210fedcba98", hash)
+	}
+
+	hash, err = c.SourcehutGetCommit(context.Background(), srv.URL, "account", "project", "v1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != "89abcdef0123456789abcdef0123456789abcdef" {
+		t.Errorf("expected commit %q, got %q", "89abcdef0123456789abcdef0123456789abcdef", hash)
+	}
+}
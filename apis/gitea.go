@@ -0,0 +1,66 @@
+package apis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+)
+
+type GiteaCommit struct {
+	SHA string `json:"sha"`
+}
+
+func (c *Client) GiteaGetCommit(ctx context.Context, site, account, project, ref string) (string, error) {
+	url := fmt.Sprintf("%s/api/v1/repos/%s/%s/git/commits/%s", c.giteaURL(site), url.PathEscape(account), url.PathEscape(project), url.PathEscape(ref))
+
+	sha, err := c.getCommit(ctx, url, "", "", func(resp []byte) (string, error) {
+		var res GiteaCommit
+		if err := json.Unmarshal(resp, &res); err != nil {
+			return "", fmt.Errorf("error unmarshalling: %v, resp: %v", err, string(resp))
+		}
+		return res.SHA, nil
+	})
+	if err != nil {
+		var rle *RateLimitError
+		if errors.As(err, &rle) {
+			return "", rle
+		}
+		return "", fmt.Errorf("error getting commit %s for %s/%s: %v", ref, account, project, err)
+	}
+
+	return sha, nil
+}
+
+func (c *Client) GiteaHasTag(ctx context.Context, site, account, project, tag string) (bool, error) {
+	url := fmt.Sprintf("%s/api/v1/repos/%s/%s/tags/%s", c.giteaURL(site), url.PathEscape(account), url.PathEscape(project), url.PathEscape(tag))
+
+	// Ignore response, we care only about errors
+	_, err := c.get(ctx, url, "", "")
+	if err != nil && err != errNotFound {
+		var rle *RateLimitError
+		if errors.As(err, &rle) {
+			return false, rle
+		}
+		return false, fmt.Errorf("error getting tag %s for %s/%s: %v", tag, account, project, err)
+	}
+	return err == nil, nil
+}
+
+// GiteaLookupTag returns tag if it exists, or the "prefix/tag" tag of a module
+// in a multi-module repo in prefix.
+func (c *Client) GiteaLookupTag(ctx context.Context, site, account, project, prefix, tag string) (string, error) {
+	for _, t := range []string{tag, path.Join(prefix, tag)} {
+		hasTag, err := c.GiteaHasTag(ctx, site, account, project, t)
+		if err != nil {
+			return "", err
+		}
+		if hasTag {
+			return t, nil
+		}
+	}
+
+	return "", fmt.Errorf("tag %v doesn't seem to exist in %s/%s", tag, account, project)
+}
@@ -1,6 +1,7 @@
 package apis
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -19,7 +20,7 @@ type GithubRef struct {
 	Ref string `json:"ref"`
 }
 
-var githubRateLimitError = fmt.Sprintf(`Github API rate limit exceeded. Please either:
+var githubRateLimitHint = fmt.Sprintf(`Please either:
 - set %s environment variable to your Github "username:personal_access_token"
   to let modules2tuple call Github API using basic authentication.
   To create a new token, navigate to https://github.com/settings/tokens/new
@@ -27,35 +28,46 @@ var githubRateLimitError = fmt.Sprintf(`Github API rate limit exceeded. Please e
 - set %s=1 or pass "-offline" flag to module2tuple to disable network access`,
 	config.GithubCredentialsKey, config.OfflineKey)
 
-func GithubGetCommit(account, project, tag string) (string, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/commits/%s", url.PathEscape(account), url.PathEscape(project), tag)
+// githubRateLimitError adds a hint about authentication to err if it's a RateLimitError.
+func githubRateLimitError(err error) (*RateLimitError, bool) {
+	var rle *RateLimitError
+	if errors.As(err, &rle) {
+		rle.hint = githubRateLimitHint
+		return rle, true
+	}
+	return nil, false
+}
+
+func (c *Client) GithubGetCommit(ctx context.Context, account, project, tag string) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/commits/%s", c.githubURL(), url.PathEscape(account), url.PathEscape(project), tag)
 
-	resp, err := get(url, config.GithubUsername, config.GithubToken)
+	sha, err := c.getCommit(ctx, url, c.GithubUsername, c.GithubToken, func(resp []byte) (string, error) {
+		var res GithubCommit
+		if err := json.Unmarshal(resp, &res); err != nil {
+			return "", fmt.Errorf("error unmarshalling: %v, resp: %v", err, string(resp))
+		}
+		return res.SHA, nil
+	})
 	if err != nil {
-		if strings.Contains(err.Error(), "API rate limit exceeded") {
-			return "", errors.New(githubRateLimitError)
+		if rle, ok := githubRateLimitError(err); ok {
+			return "", rle
 		}
 		return "", fmt.Errorf("error getting commit %s for %s/%s: %v", tag, account, project, err)
 	}
 
-	var res GithubCommit
-	if err := json.Unmarshal(resp, &res); err != nil {
-		return "", fmt.Errorf("error unmarshalling: %v, resp: %v", err, string(resp))
-	}
-
-	return res.SHA, nil
+	return sha, nil
 }
 
-func GithubHasTag(account, project, tag string) (bool, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/git/refs/tags/%s", url.PathEscape(account), url.PathEscape(project), tag)
+func (c *Client) GithubHasTag(ctx context.Context, account, project, tag string) (bool, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/git/refs/tags/%s", c.githubURL(), url.PathEscape(account), url.PathEscape(project), tag)
 
-	resp, err := get(url, config.GithubUsername, config.GithubToken)
+	resp, err := c.get(ctx, url, c.GithubUsername, c.GithubToken)
 	if err != nil {
 		if err == errNotFound {
 			return false, nil
 		}
-		if strings.Contains(err.Error(), "API rate limit exceeded") {
-			return false, errors.New(githubRateLimitError)
+		if rle, ok := githubRateLimitError(err); ok {
+			return false, rle
 		}
 		return false, fmt.Errorf("error getting refs for %s/%s: %v", account, project, err)
 	}
@@ -74,13 +86,13 @@ func GithubHasTag(account, project, tag string) (bool, error) {
 	return true, nil
 }
 
-func GithubListTags(account, project, prefix string) ([]string, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/git/refs/tags/%s", url.PathEscape(account), url.PathEscape(project), url.PathEscape(prefix))
+func (c *Client) GithubListTags(ctx context.Context, account, project, prefix string) ([]string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/git/refs/tags/%s", c.githubURL(), url.PathEscape(account), url.PathEscape(project), url.PathEscape(prefix))
 
-	resp, err := get(url, config.GithubUsername, config.GithubToken)
+	resp, err := c.get(ctx, url, c.GithubUsername, c.GithubToken)
 	if err != nil {
-		if strings.Contains(err.Error(), "API rate limit exceeded") {
-			return nil, errors.New(githubRateLimitError)
+		if rle, ok := githubRateLimitError(err); ok {
+			return nil, rle
 		}
 		return nil, fmt.Errorf("error getting refs for %s/%s: %v", account, project, err)
 	}
@@ -98,8 +110,50 @@ func GithubListTags(account, project, prefix string) ([]string, error) {
 	return res, nil
 }
 
-func GithubLookupTag(account, project, path, tag string) (string, error) {
-	hasTag, err := GithubHasTag(account, project, tag)
+type GithubTag struct {
+	Name   string       `json:"name"`
+	Commit GithubCommit `json:"commit"`
+}
+
+// githubMaxTagPages limits how many pages of tags GithubListTagsAt looks through.
+const githubMaxTagPages = 10
+
+// GithubListTagsAt returns names of tags pointing at commit, which can be
+// abbreviated. Only the most recent tags are looked at.
+func (c *Client) GithubListTagsAt(ctx context.Context, account, project, commit string) ([]string, error) {
+	const perPage = 100
+
+	var res []string
+	for page := 1; page <= githubMaxTagPages; page++ {
+		url := fmt.Sprintf("%s/repos/%s/%s/tags?per_page=%d&page=%d", c.githubURL(), url.PathEscape(account), url.PathEscape(project), perPage, page)
+
+		resp, err := c.get(ctx, url, c.GithubUsername, c.GithubToken)
+		if err != nil {
+			if rle, ok := githubRateLimitError(err); ok {
+				return nil, rle
+			}
+			return nil, fmt.Errorf("error getting tags for %s/%s: %v", account, project, err)
+		}
+
+		var tags []GithubTag
+		if err := json.Unmarshal(resp, &tags); err != nil {
+			return nil, fmt.Errorf("error unmarshalling: %v, resp: %v", err, string(resp))
+		}
+		for _, t := range tags {
+			if strings.HasPrefix(t.Commit.SHA, commit) {
+				res = append(res, t.Name)
+			}
+		}
+		if len(tags) < perPage {
+			break
+		}
+	}
+
+	return res, nil
+}
+
+func (c *Client) GithubLookupTag(ctx context.Context, account, project, path, tag string) (string, error) {
+	hasTag, err := c.GithubHasTag(ctx, account, project, tag)
 	if err != nil {
 		return "", err
 	}
@@ -110,7 +164,7 @@ func GithubLookupTag(account, project, path, tag string) (string, error) {
 	}
 
 	// tag was not found, try to look it up
-	allTags, err := GithubListTags(account, project, path)
+	allTags, err := c.GithubListTags(ctx, account, project, path)
 	if err != nil {
 		return "", err
 	}
@@ -126,12 +180,15 @@ func GithubLookupTag(account, project, path, tag string) (string, error) {
 	return "", fmt.Errorf("tag %v doesn't seem to exist in %s/%s", tag, account, project)
 }
 
-func GithubHasContentsAtPath(account, project, path, tag string) (bool, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/%s?ref=%s", url.PathEscape(account), url.PathEscape(project), path, tag)
+func (c *Client) GithubHasContentsAtPath(ctx context.Context, account, project, path, tag string) (bool, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/contents/%s?ref=%s", c.githubURL(), url.PathEscape(account), url.PathEscape(project), path, tag)
 
 	// Ignore response, we care only about errors
-	_, err := get(url, config.GithubUsername, config.GithubToken)
+	_, err := c.get(ctx, url, c.GithubUsername, c.GithubToken)
 	if err != nil && err != errNotFound {
+		if rle, ok := githubRateLimitError(err); ok {
+			return false, rle
+		}
 		return false, err
 	}
 	return err == nil, nil
@@ -3,7 +3,10 @@
 
 package apis
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func TestGithubGetCommit(t *testing.T) {
 	examples := []struct {
@@ -14,7 +17,7 @@ func TestGithubGetCommit(t *testing.T) {
 	}
 
 	for i, x := range examples {
-		hash, err := GithubGetCommit(x.account, x.project, x.ref)
+		hash, err := NewClient().GithubGetCommit(context.Background(), x.account, x.project, x.ref)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -38,7 +41,7 @@ func TestGithubLookupTag(t *testing.T) {
 	}
 
 	for i, x := range examples {
-		tag, err := GithubLookupTag(x.account, x.project, x.packageSuffix, x.given)
+		tag, err := NewClient().GithubLookupTag(context.Background(), x.account, x.project, x.packageSuffix, x.given)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -1,7 +1,9 @@
 package apis
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 )
@@ -10,22 +12,24 @@ type GitlabCommit struct {
 	SHA string `json:"id"`
 }
 
-func GitlabGetCommit(site, account, project, commit string) (string, error) {
-	if site == "" {
-		site = "https://gitlab.com"
-	}
+func (c *Client) GitlabGetCommit(ctx context.Context, site, account, project, commit string) (string, error) {
 	projectID := url.PathEscape(fmt.Sprintf("%s/%s", account, project))
-	url := fmt.Sprintf("%s/api/v4/projects/%s/repository/commits/%s", site, projectID, commit)
+	url := fmt.Sprintf("%s/api/v4/projects/%s/repository/commits/%s", c.gitlabURL(site), projectID, commit)
 
-	resp, err := get(url, "", "")
+	sha, err := c.getCommit(ctx, url, "", "", func(resp []byte) (string, error) {
+		var res GitlabCommit
+		if err := json.Unmarshal(resp, &res); err != nil {
+			return "", fmt.Errorf("error unmarshalling: %v, resp: %v", err, string(resp))
+		}
+		return res.SHA, nil
+	})
 	if err != nil {
+		var rle *RateLimitError
+		if errors.As(err, &rle) {
+			return "", rle
+		}
 		return "", fmt.Errorf("error getting commit %s for %s/%s: %v", commit, account, project, err)
 	}
 
-	var res GitlabCommit
-	if err := json.Unmarshal(resp, &res); err != nil {
-		return "", fmt.Errorf("error unmarshalling: %v, resp: %v", err, string(resp))
-	}
-
-	return res.SHA, nil
+	return sha, nil
 }
@@ -3,7 +3,10 @@
 
 package apis
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func TestGitlabGetCommit(t *testing.T) {
 	examples := []struct {
@@ -14,7 +17,7 @@ func TestGitlabGetCommit(t *testing.T) {
 	}
 
 	for i, x := range examples {
-		sha, err := GitlabGetCommit(x.site, x.account, x.project, x.ref)
+		sha, err := NewClient().GitlabGetCommit(context.Background(), x.site, x.account, x.project, x.ref)
 		if err != nil {
 			t.Fatal(err)
 		}
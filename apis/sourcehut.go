@@ -0,0 +1,46 @@
+package apis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/dmgk/modules2tuple/v2/config"
+)
+
+type SourcehutCommit struct {
+	ID string `json:"id"`
+}
+
+type sourcehutLog struct {
+	Results []SourcehutCommit `json:"results"`
+}
+
+func (c *Client) SourcehutGetCommit(ctx context.Context, site, account, project, ref string) (string, error) {
+	url := fmt.Sprintf("%s/api/~%s/repos/%s/log/%s", c.sourcehutURL(site), url.PathEscape(account), url.PathEscape(project), url.PathEscape(ref))
+
+	id, err := c.getCommit(ctx, url, "", c.SourcehutToken, func(resp []byte) (string, error) {
+		var res sourcehutLog
+		if err := json.Unmarshal(resp, &res); err != nil {
+			return "", fmt.Errorf("error unmarshalling: %v, resp: %v", err, string(resp))
+		}
+		if len(res.Results) == 0 {
+			return "", fmt.Errorf("commit %s for ~%s/%s not found", ref, account, project)
+		}
+		return res.Results[0].ID, nil
+	})
+	if err != nil {
+		var rle *RateLimitError
+		if errors.As(err, &rle) {
+			return "", rle
+		}
+		if c.SourcehutToken == "" {
+			return "", fmt.Errorf("error getting commit %s for ~%s/%s: %v (SourceHut API requires OAuth token, set %s environment variable)", ref, account, project, err, config.SourcehutTokenKey)
+		}
+		return "", fmt.Errorf("error getting commit %s for ~%s/%s: %v", ref, account, project, err)
+	}
+
+	return id, nil
+}
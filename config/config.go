@@ -2,11 +2,14 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 const (
 	GithubCredentialsKey = "M2T_GITHUB"
+	SourcehutTokenKey    = "M2T_SOURCEHUT"
 	OfflineKey           = "M2T_OFFLINE"
 	DebugKey             = "M2T_DEBUG"
 )
@@ -14,15 +17,50 @@ const (
 var (
 	GithubToken    string
 	GithubUsername string
+	SourcehutToken string
+	GithubURL      string
+	GitlabURL      string
 	Offline        bool
 	Debug          bool
 	ShowVersion    bool
+	Format         string
+	Distinfo       string
+	DistSubdir     string
+	Update         string
+	MirrorsPath    string
+	CacheDir       string
+	CacheTTL       = 24 * time.Hour
+	NoCache        bool
+	ClearCache     bool
+	RateLimitWait  = time.Hour
+	RequestTimeout = 30 * time.Second
+	Timeout        time.Duration
+	ProxyFallback  bool
+	GoProxy        string
+	ModCache       string
+	PreferTags     bool
+	ModuleVersions bool
+	SkipUnused     bool
+	Cleanup        bool
+
+	// DefaultMirrorsPath is the user mirrors file location, if it exists
+	DefaultMirrorsPath string
 )
 
 func init() {
 	Offline = os.Getenv(OfflineKey) != ""
 	Debug = os.Getenv(DebugKey) != ""
 
+	if dir, err := os.UserConfigDir(); err == nil {
+		DefaultMirrorsPath = filepath.Join(dir, "modules2tuple", "mirrors.toml")
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		CacheDir = filepath.Join(dir, "modules2tuple")
+	}
+	ModCache = defaultModCache()
+
+	SourcehutToken = os.Getenv(SourcehutTokenKey)
+
 	githubCredentials := os.Getenv(GithubCredentialsKey)
 	if githubCredentials != "" {
 		parts := strings.Split(githubCredentials, ":")
@@ -32,3 +70,17 @@ func init() {
 		}
 	}
 }
+
+// defaultModCache returns the Go module cache location the same way the go command does.
+func defaultModCache() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	if gopath := filepath.SplitList(os.Getenv("GOPATH")); len(gopath) > 0 && gopath[0] != "" {
+		return filepath.Join(gopath[0], "pkg", "mod")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, "go", "pkg", "mod")
+	}
+	return ""
+}
@@ -7,12 +7,32 @@ import (
 	"github.com/dmgk/modules2tuple/v2/config"
 )
 
+// Logger receives debug messages, *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Stderr is a Logger printing messages to stderr as-is.
+var Stderr Logger = stderrLogger{}
+
+type stderrLogger struct{}
+
+func (stderrLogger) Printf(format string, v ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, v...)
+}
+
+// Print prints debug message to stderr if debug output is enabled.
+//
+// Deprecated: set tuple.Options.Logger instead.
 func Print(v ...interface{}) {
 	if config.Debug {
 		fmt.Fprint(os.Stderr, v...)
 	}
 }
 
+// Printf prints formatted debug message to stderr if debug output is enabled.
+//
+// Deprecated: set tuple.Options.Logger instead.
 func Printf(format string, v ...interface{}) {
 	if config.Debug {
 		fmt.Fprintf(os.Stderr, format, v...)
@@ -0,0 +1,117 @@
+package distinfo
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MissingError is returned when some of the distfiles couldn't be found.
+type MissingError []string
+
+func (err MissingError) Error() string {
+	return fmt.Sprintf("missing distfiles:\n\t%s", strings.Join(err, "\n\t"))
+}
+
+// timeNow returns distinfo TIMESTAMP, replaced in tests.
+var timeNow = time.Now
+
+// Write writes distinfo for distfiles names to w. Distfiles are looked up in
+// src, which is either DISTDIR or a base http(s) URL of a distfiles mirror.
+// If subdir is not empty, distfile names are prefixed with it as with
+// DIST_SUBDIR, and distfiles are looked up in src/subdir first, then in src.
+// All distfiles are checked and MissingError listing all missing ones is returned.
+// Downloads are abandoned once ctx is done, and if a server doesn't start
+// responding within timeout, if it's non-zero.
+func Write(ctx context.Context, w io.Writer, src string, names []string, subdir string, timeout time.Duration) error {
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			ResponseHeaderTimeout: timeout,
+		},
+	}
+
+	var (
+		lines   []string
+		missing MissingError
+	)
+
+	lines = append(lines, fmt.Sprintf("TIMESTAMP = %d", timeNow().Unix()))
+
+	for _, name := range names {
+		sum, size, err := checksum(ctx, client, src, subdir, name)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				missing = append(missing, name)
+				continue
+			}
+			return err
+		}
+		if subdir != "" {
+			name = path.Join(subdir, name)
+		}
+		lines = append(lines, fmt.Sprintf("SHA256 (%s) = %x", name, sum))
+		lines = append(lines, fmt.Sprintf("SIZE (%s) = %d", name, size))
+	}
+
+	if len(missing) > 0 {
+		return missing
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func checksum(ctx context.Context, client *http.Client, src, subdir, name string) ([]byte, int64, error) {
+	r, err := open(ctx, client, src, path.Join(subdir, name))
+	if subdir != "" && errors.Is(err, os.ErrNotExist) {
+		r, err = open(ctx, client, src, name)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer r.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, r)
+	if err != nil {
+		return nil, 0, fmt.Errorf("distinfo.checksum %s: %v", name, err)
+	}
+
+	return h.Sum(nil), size, nil
+}
+
+func open(ctx context.Context, client *http.Client, src, name string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return os.Open(filepath.Join(src, filepath.FromSlash(name)))
+	}
+
+	u := strings.TrimSuffix(src, "/") + "/" + name
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("distinfo.open %s: %v", u, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("distinfo.open %s: %v", u, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, os.ErrNotExist
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("distinfo.open %s: status %d", u, resp.StatusCode)
+	}
+}
@@ -0,0 +1,126 @@
+package distinfo
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func init() {
+	timeNow = func() time.Time {
+		return time.Unix(1600000000, 0)
+	}
+}
+
+var distfiles = map[string]string{
+	"pkg-errors-v0.9.1_GH0.tar.gz":          "errors",
+	"hashicorp-vault-api-v1.0.4_GH0.tar.gz": "vault",
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range distfiles {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+
+	names := []string{"hashicorp-vault-api-v1.0.4_GH0.tar.gz", "pkg-errors-v0.9.1_GH0.tar.gz"}
+
+	expected := `TIMESTAMP = 1600000000
+SHA256 (go/hashicorp-vault-api-v1.0.4_GH0.tar.gz) = e6f0a1fbb43c89196dcfcbef85908f19ab4c5f7cc4f4c452284697757683d7ef
+SIZE (go/hashicorp-vault-api-v1.0.4_GH0.tar.gz) = 5
+SHA256 (go/pkg-errors-v0.9.1_GH0.tar.gz) = be4bd56772770471b639574d919a92de16c0e522ea5601e2892461691bdf9545
+SIZE (go/pkg-errors-v0.9.1_GH0.tar.gz) = 6
+`
+
+	for _, src := range []string{dir, srv.URL} {
+		var buf bytes.Buffer
+		if err := Write(context.Background(), &buf, src, names, "go", time.Second); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != expected {
+			t.Errorf("%s: expected distinfo\n%s\n, got\n%s\n", src, expected, buf.String())
+		}
+	}
+
+	// DISTDIR with distfiles in DIST_SUBDIR, as after "make fetch"
+	distdir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(distdir, "go"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range distfiles {
+		if err := os.WriteFile(filepath.Join(distdir, "go", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// same name outside of DIST_SUBDIR is ignored
+	if err := os.WriteFile(filepath.Join(distdir, names[0]), []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv = httptest.NewServer(http.FileServer(http.Dir(distdir)))
+	defer srv.Close()
+
+	for _, src := range []string{distdir, srv.URL} {
+		var buf bytes.Buffer
+		if err := Write(context.Background(), &buf, src, names, "go", time.Second); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != expected {
+			t.Errorf("%s: expected distinfo\n%s\n, got\n%s\n", src, expected, buf.String())
+		}
+	}
+}
+
+func TestWriteMissing(t *testing.T) {
+	dir := t.TempDir()
+
+	names := []string{"a-b-v1.0.0_GH0.tar.gz", "c-d-v2.0.0_GL0.tar.gz"}
+
+	var buf bytes.Buffer
+	err := Write(context.Background(), &buf, dir, names, "", time.Second)
+	missing, ok := err.(MissingError)
+	if !ok {
+		t.Fatalf("expected MissingError, got %v", err)
+	}
+	if len(missing) != 2 || missing[0] != names[0] || missing[1] != names[1] {
+		t.Errorf("expected missing distfiles %q, got %q", names, missing)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got %q", buf.String())
+	}
+}
+
+func TestWriteTimeout(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	names := []string{"a-b-v1.0.0_GH0.tar.gz"}
+
+	var buf bytes.Buffer
+	if err := Write(context.Background(), &buf, srv.URL, names, "", 50*time.Millisecond); err == nil {
+		t.Error("expected hung server to time out")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Write(ctx, &buf, srv.URL, names, "", 0); err == nil {
+		t.Error("expected canceled context to abort download")
+	}
+}
@@ -2,4 +2,8 @@ module github.com/dmgk/modules2tuple/v2
 
 go 1.18
 
-require github.com/sergi/go-diff v1.1.0
+require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/sergi/go-diff v1.1.0
+	golang.org/x/mod v0.20.0
+)
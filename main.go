@@ -1,14 +1,22 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"html/template"
 	"os"
+	"os/signal"
 	"path"
+	"strings"
 
+	"github.com/dmgk/modules2tuple/v2/apis"
 	"github.com/dmgk/modules2tuple/v2/config"
+	"github.com/dmgk/modules2tuple/v2/distinfo"
+	"github.com/dmgk/modules2tuple/v2/makefile"
 	"github.com/dmgk/modules2tuple/v2/parser"
+	"github.com/dmgk/modules2tuple/v2/tuple"
 )
 
 var version = "devel"
@@ -21,22 +29,208 @@ func main() {
 
 	args := flag.Args()
 
+	if config.ClearCache {
+		if err := (&apis.Client{CacheDir: config.CacheDir}).ClearCache(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if len(args) == 0 {
+			os.Exit(0)
+		}
+	}
+
 	if len(args) == 0 {
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	res, err := parser.Load(args[0])
+	switch config.Format {
+	case "makefile", "modules", "packages", "json":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown output format: %q\n", config.Format)
+		os.Exit(1)
+	}
+
+	// cancel outstanding lookups on Ctrl-C or when the overall timeout expires
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+	}
+
+	opts := tuple.DefaultOptions()
+	mirrors, err := loadMirrors()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	opts.Mirrors = mirrors
+
+	if args[0] == "diff" {
+		if len(args) != 3 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		oldRes, err := load(ctx, args[1], opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		newRes, err := load(ctx, args[2], opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		changes, err := parser.Diff(oldRes, newRes)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(changes)
+		return
+	}
+
+	if config.Format == "modules" && config.Distinfo == "" && config.Update == "" {
+		// GO_MODULES entries only need module paths and versions, don't
+		// look up mirrors online
+		opts.Offline = true
+		opts.PreferTags = false
+	}
+
+	res, err := load(ctx, args[0], opts)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
-	fmt.Println(res)
+
+	if config.Distinfo != "" {
+		if res.HasErrors() {
+			fmt.Fprintln(os.Stderr, "warning: some packages couldn't be processed, distinfo is incomplete")
+		}
+		if err := distinfo.Write(ctx, os.Stdout, config.Distinfo, res.Distfiles(), config.DistSubdir, config.RequestTimeout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Update != "" {
+		diff, err := makefile.UpdateFile(config.Update, makefileOutput(res))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Print(diff)
+		return
+	}
+
+	switch config.Format {
+	case "json":
+		out, err := json.MarshalIndent(res, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(string(out))
+	case "modules":
+		fmt.Println(res.GoModules())
+	case "packages":
+		fmt.Println(res.PackageReport())
+	default:
+		fmt.Println(makefileOutput(res))
+	}
 }
 
-var usageTemplate = template.Must(template.New("Usage").Parse(`usage: {{.basename}} [options] modules.txt
+// makefileOutput returns tuples for the port Makefile, with optional parts if requested.
+func makefileOutput(res *parser.Result) string {
+	return res.Makefile(parser.MakefileOptions{
+		Versions: config.ModuleVersions,
+		Cleanup:  config.Cleanup,
+	})
+}
+
+// loadMirrors loads user-defined mirrors. Default mirrors file is optional.
+func loadMirrors() (*tuple.Mirrors, error) {
+	if config.MirrorsPath == "" {
+		return nil, nil
+	}
+	m, err := tuple.LoadMirrorsFile(config.MirrorsPath)
+	if os.IsNotExist(err) && config.MirrorsPath == config.DefaultMirrorsPath {
+		return nil, nil
+	}
+	return m, err
+}
+
+// load parses tuples from the file at path according to its name.
+func load(ctx context.Context, p string, opts *tuple.Options) (*parser.Result, error) {
+	switch base := path.Base(p); {
+	case base == "go.mod":
+		return parser.LoadGoMod(ctx, p, opts)
+	case base == "go.work":
+		return parser.LoadGoWork(ctx, p, opts)
+	case strings.HasPrefix(base, "Makefile"):
+		return parser.LoadMakefile(p)
+	default:
+		return parser.Load(ctx, p, opts)
+	}
+}
+
+var usageTemplate = template.Must(template.New("Usage").Parse(`usage: {{.basename}} [options] modules.txt|go.mod|go.work
+       {{.basename}} [options] diff old new
 
 Options:
+    -format   output format, "makefile", "modules", "packages" or "json" (default makefile)
+    -distinfo <dir|url>
+              print distinfo for all tuples, with distfiles looked up in DISTDIR or at base URL
+              of a distfiles mirror, in <subdir> under it first if "-distsubdir" is set
+    -distsubdir <subdir>
+              DIST_SUBDIR to prefix distinfo entries with
+    -update <Makefile>
+              replace GH_TUPLE, GL_TUPLE and post-extract target in the port Makefile
+              and print the diff
+    -module-versions
+              list the original Go module path, version and declared go version of each tuple
+              in a comment following the tuples
+    -skip-unused
+              skip modules listed in modules.txt that don't provide any vendored packages,
+              they're listed in a comment instead
+    -cleanup  remove files that aren't part of vendored packages from vendored modules
+              in post-extract, to cut WRKSRC size
+    -mirrors <mirrors.toml>
+              load additional mirrors from file (default {{.mirrors}})
+    -modcache <dir>
+              Go module cache to read module origins (repository, tag and commit ID) from
+              in offline mode, empty to disable (default {{.modCache}})
+    -prefer-tags
+              use Github tags instead of commit IDs of pseudo-versions if they point at the same commit,
+              tags are looked up in the module cache first
+    -proxy-fallback
+              fetch modules with unknown mirrors from the Go module proxy instead of
+              listing them for manual lookup
+    -goproxy <url>
+              Go module proxy URL for -proxy-fallback (default {{.goProxy}})
+    -cache-dir <dir>
+              cache Github and Gitlab API responses in directory (default {{.cacheDir}})
+    -cache-ttl <duration>
+              expire cached tag lookups after duration, commit lookups never expire (default {{.cacheTTL}})
+    -no-cache don't use API responses cache
+    -clear-cache
+              remove all cached API responses
+    -github-api <url>
+              Github API base URL, e.g. https://github.example.com/api/v3 for Github Enterprise
+              (default {{.githubURL}})
+    -gitlab-site <url>
+              default Gitlab site URL (default {{.gitlabURL}})
+    -timeout <duration>
+              abort if all lookups don't finish within duration (default no timeout)
+    -request-timeout <duration>
+              abort single API or vanity import request after duration, or distfile download
+              if the server doesn't respond within it (default {{.requestTimeout}})
+    -rate-limit-wait <duration>
+              wait up to duration in total for API rate limit reset, 0 to fail immediately
+              (default {{.rateLimitWait}})
     -offline  disable all network access (env M2T_OFFLINE, default {{.offline}})
     -debug    print debug info (env M2T_DEBUG, default {{.debug}})
     -v        show version
@@ -47,11 +241,73 @@ Usage:
     $ go mod vendor
     $ {{.basename}} vendor/modules.txt
 
+    Or run {{.basename}} with go.mod directly, go.sum next to it is used to skip modules
+    that don't provide any source code:
+
+    $ {{.basename}} go.mod
+
+    For multi-module workspaces, run {{.basename}} with go.work, requirements of all
+    workspace modules are merged and workspace modules themselves are symlinked:
+
+    $ {{.basename}} go.work
+
+User-defined mirrors:
+    Mirrors for packages not known to {{.basename}} can be added to mirrors.toml,
+    they're looked up before the built-in ones:
+
+    # fixed mapping, matched by package prefix
+    [[mirror]]
+    prefix = "code.cloudfoundry.org/gofileutils"
+    account = "cloudfoundry"
+    project = "gofileutils"
+
+    # regexp mapping, account, project and module can refer to the pattern captures
+    [[mirror]]
+    prefix = "code.cloudfoundry.org"
+    pattern = '\Acode\.cloudfoundry\.org/([0-9A-Za-z][-0-9A-Za-z]+)\z'
+    source = "github"  # or "gitlab", "sourcehut", "gitea", "bitbucket",
+                       # with optional site = "https://gitlab.example.com"
+    account = "cloudfoundry"
+    project = "$1"
+
+    # packages required under multiple import paths, fetched once as the canonical
+    # Github account and project
+    [[alias]]
+    path = "github.com/Sirupsen/logrus"
+    account = "sirupsen"
+    project = "logrus"
+
+Comparing dependencies:
+    Run "{{.basename}} diff" with two modules.txt, go.mod or go.work files, or with
+    the existing port Makefile and the new modules.txt, to see which tuples were added,
+    removed or changed versions:
+
+    $ {{.basename}} diff Makefile vendor/modules.txt
+
+Generating distinfo:
+    With distfiles already fetched into DISTDIR, run {{.basename}} with "-distinfo"
+    to write distinfo without downloading all archives again:
+
+    $ {{.basename}} -distinfo /usr/ports/distfiles vendor/modules.txt > distinfo
+
+Output formats:
+    - "makefile" prints GH_TUPLE/GL_TUPLE and post-extract target for the port Makefile,
+      SourceHut, Gitea and Bitbucket dependencies are added to MASTER_SITES/DISTFILES
+      and moved in place, as are module zip files with "-proxy-fallback" and of SourceHut
+      and Gitea projects of the same name that would be extracted into the same directory
+    - "modules" prints GO_MODULES with all modules fetched from the Go module proxy
+      (USES=go:modules), no mirror lookups or symlinks are needed, so it always runs offline
+    - "packages" prints packages vendored from each module listed in modules.txt, with
+      an estimate of how much of the module is unused if its sources are in the module cache
+    - "json" prints all tuples and errors for use by other tools
+
 When running in offline mode:
     - mirrors are looked up using static list and some may not be resolved
     - milti-module repos and version suffixes ("/v2") are not automatically handled
     - Github tags for modules ("v1.2.3" vs "api/v1.2.3") are not automatically resolved
     - Gitlab commit IDs are not resolved to the full 40-char IDs
+    unless module origins are recorded in the module cache, run "go mod download"
+    with a recent Go toolchain first to have them resolved offline
 `))
 
 func init() {
@@ -60,12 +316,42 @@ func init() {
 	flag.BoolVar(&config.Offline, "offline", config.Offline, "")
 	flag.BoolVar(&config.Debug, "debug", config.Debug, "")
 	flag.BoolVar(&config.ShowVersion, "v", false, "")
+	flag.StringVar(&config.Format, "format", "makefile", "")
+	flag.StringVar(&config.Distinfo, "distinfo", "", "")
+	flag.StringVar(&config.DistSubdir, "distsubdir", "", "")
+	flag.StringVar(&config.Update, "update", "", "")
+	flag.BoolVar(&config.ModuleVersions, "module-versions", false, "")
+	flag.BoolVar(&config.SkipUnused, "skip-unused", false, "")
+	flag.BoolVar(&config.Cleanup, "cleanup", false, "")
+	flag.StringVar(&config.MirrorsPath, "mirrors", config.DefaultMirrorsPath, "")
+	flag.BoolVar(&config.PreferTags, "prefer-tags", false, "")
+	flag.BoolVar(&config.ProxyFallback, "proxy-fallback", false, "")
+	flag.StringVar(&config.GoProxy, "goproxy", tuple.DefaultGoProxy, "")
+	flag.StringVar(&config.ModCache, "modcache", config.ModCache, "")
+	flag.StringVar(&config.CacheDir, "cache-dir", config.CacheDir, "")
+	flag.DurationVar(&config.CacheTTL, "cache-ttl", config.CacheTTL, "")
+	flag.BoolVar(&config.NoCache, "no-cache", false, "")
+	flag.BoolVar(&config.ClearCache, "clear-cache", false, "")
+	flag.StringVar(&config.GithubURL, "github-api", apis.DefaultGithubURL, "")
+	flag.StringVar(&config.GitlabURL, "gitlab-site", apis.DefaultGitlabURL, "")
+	flag.DurationVar(&config.Timeout, "timeout", 0, "")
+	flag.DurationVar(&config.RequestTimeout, "request-timeout", config.RequestTimeout, "")
+	flag.DurationVar(&config.RateLimitWait, "rate-limit-wait", config.RateLimitWait, "")
 
 	flag.Usage = func() {
 		err := usageTemplate.Execute(os.Stderr, map[string]interface{}{
-			"basename": basename,
-			"offline":  config.Offline,
-			"debug":    config.Debug,
+			"basename":       basename,
+			"offline":        config.Offline,
+			"debug":          config.Debug,
+			"mirrors":        config.DefaultMirrorsPath,
+			"cacheDir":       config.CacheDir,
+			"cacheTTL":       config.CacheTTL,
+			"rateLimitWait":  config.RateLimitWait,
+			"requestTimeout": config.RequestTimeout,
+			"githubURL":      config.GithubURL,
+			"gitlabURL":      config.GitlabURL,
+			"goProxy":        config.GoProxy,
+			"modCache":       config.ModCache,
 		})
 		if err != nil {
 			panic(err)
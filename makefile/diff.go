@@ -0,0 +1,112 @@
+package makefile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffContext is the number of unchanged lines shown around changes.
+const diffContext = 3
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// Diff returns unified diff between old and new contents of the file name.
+func Diff(name, old, new string) string {
+	ops := diffLines(strings.Split(old, "\n"), strings.Split(new, "\n"))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", name, name)
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// hunk start, include leading context
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		// extend hunk while changes are closer than 2*diffContext lines apart
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			n := end
+			for n < len(ops) && ops[n].kind == ' ' {
+				n++
+			}
+			if n == len(ops) || n-end > 2*diffContext {
+				end += diffContext
+				if end > len(ops) {
+					end = len(ops)
+				}
+				break
+			}
+			end = n
+		}
+
+		oldStart, newStart := 1, 1
+		for _, op := range ops[:start] {
+			if op.kind != '+' {
+				oldStart++
+			}
+			if op.kind != '-' {
+				newStart++
+			}
+		}
+		var oldLen, newLen int
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				oldLen++
+			}
+			if op.kind != '-' {
+				newLen++
+			}
+		}
+
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLen, newStart, newLen)
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(&b, "%c%s\n", op.kind, op.line)
+		}
+
+		i = end
+	}
+
+	return b.String()
+}
+
+// diffLines computes line edit script between a and b.
+func diffLines(a, b []string) []diffOp {
+	dmp := diffmatchpatch.New()
+	ca, cb, lines := dmp.DiffLinesToChars(strings.Join(a, "\n")+"\n", strings.Join(b, "\n")+"\n")
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(ca, cb, false), lines)
+
+	var res []diffOp
+	for _, d := range diffs {
+		var kind byte
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			kind = ' '
+		case diffmatchpatch.DiffDelete:
+			kind = '-'
+		case diffmatchpatch.DiffInsert:
+			kind = '+'
+		}
+		for _, line := range strings.SplitAfter(d.Text, "\n") {
+			if line != "" {
+				res = append(res, diffOp{kind, strings.TrimSuffix(line, "\n")})
+			}
+		}
+	}
+
+	return res
+}
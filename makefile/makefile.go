@@ -0,0 +1,338 @@
+package makefile
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	// GH_TUPLE= and GL_TUPLE= assignments
+	tupleVarRe = regexp.MustCompile(`\AG[HL]_TUPLE[ \t]*=`)
+	// MASTER_SITES+= and DISTFILES+= assignments for tuples fetched as regular distfiles
+	distfileVarRe = regexp.MustCompile(`\A(?:MASTER_SITES|DISTFILES)\+=`)
+	// MASTER_SITES+= assignments
+	masterSitesVarRe = regexp.MustCompile(`\AMASTER_SITES\+=`)
+	// values of distfile assignments generated by modules2tuple
+	distfileValueRe = regexp.MustCompile(`\?dummy=/:\w+|_[A-Z]{2}0\.(?:tar\.gz|zip):\w+`)
+	// "Mirrors ... not currently known" and "Errors found" comments following tuples
+	commentRe = regexp.MustCompile(`\A\t\t#`)
+	// post-extract target
+	postExtractRe = regexp.MustCompile(`\Apost-extract:[ \t]*\z`)
+	// post-extract recipe lines generated by modules2tuple
+	recipeRe = regexp.MustCompile(`\A\t@\$\{(?:MKDIR|RM|RLN|MV|FIND)\} .*\$\{WRKSRC`)
+	// insertion points for tuples, if Makefile doesn't have them yet
+	insertAfterRe = []*regexp.Regexp{
+		regexp.MustCompile(`\AUSE_G(?:ITHUB|ITLAB)[ \t]*[?+]?=`),
+		regexp.MustCompile(`\AUSES[ \t]*[?+]?=`),
+	}
+	// post-extract insertion point, if Makefile doesn't have it yet
+	includeRe = regexp.MustCompile(`\A\.include[ \t]+<bsd\.port(?:\.post)?\.mk>`)
+)
+
+// UpdateFile replaces G{H,L}_TUPLE and post-extract target in Makefile at path
+// with generated contents and returns the diff of changes. File is not
+// rewritten if there are no changes.
+func UpdateFile(path, generated string) (string, error) {
+	old, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	new, err := Update(string(old), generated)
+	if err != nil {
+		return "", err
+	}
+	if new == string(old) {
+		return "", nil
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(new), fi.Mode()); err != nil {
+		return "", err
+	}
+
+	return Diff(path, string(old), new), nil
+}
+
+// Update replaces G{H,L}_TUPLE variables, MASTER_SITES and DISTFILES additions
+// for tuples fetched as regular distfiles, comments following them and
+// modules2tuple-generated lines of post-extract target in Makefile contents
+// with generated ones. Unrelated lines are preserved.
+func Update(makefile, generated string) (string, error) {
+	tuples, recipe := split(generated)
+
+	lines := strings.Split(makefile, "\n")
+
+	var (
+		res             []string
+		tuplesDone      bool
+		postExtractDone bool
+	)
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		switch {
+		case isTupleVar(lines, i):
+			i = skipTuples(lines, i)
+			if tuplesDone {
+				res = dropBlank(res, lines, i)
+				continue
+			}
+			if len(tuples) == 0 {
+				res = dropBlank(res, lines, i)
+			} else {
+				res = append(res, tuples...)
+			}
+			tuplesDone = true
+		case postExtractRe.MatchString(line) && !postExtractDone:
+			var body []string
+			i, body = updateRecipe(lines, i+1, recipe)
+			postExtractDone = true
+			if len(body) == 0 {
+				res = dropBlank(res, lines, i)
+				continue
+			}
+			res = append(res, line)
+			res = append(res, body...)
+		default:
+			res = append(res, line)
+			i++
+		}
+	}
+
+	if !tuplesDone && len(tuples) > 0 {
+		n := insertionPoint(res)
+		if n < 0 {
+			return "", errors.New("unable to find where to insert tuples, please add empty GH_TUPLE= to the Makefile")
+		}
+		res = insert(res, n, append([]string{""}, tuples...))
+	}
+
+	if !postExtractDone && len(recipe) > 0 {
+		target := append([]string{"post-extract:"}, recipe...)
+		n := len(res)
+		for i := len(res) - 1; i >= 0; i-- {
+			if includeRe.MatchString(res[i]) {
+				n = i
+				break
+			}
+		}
+		if n == len(res) {
+			// no .include, append at the end
+			for n > 0 && res[n-1] == "" {
+				n--
+			}
+			res = insert(res, n, append([]string{""}, target...))
+		} else {
+			res = insert(res, n, append(target, ""))
+		}
+	}
+
+	return strings.Join(res, "\n"), nil
+}
+
+// TupleVars returns entries of all G{H,L}_TUPLE variables in Makefile contents,
+// keyed by variable name.
+func TupleVars(makefile string) map[string][]string {
+	res := map[string][]string{}
+
+	lines := strings.Split(makefile, "\n")
+	for i := 0; i < len(lines); i++ {
+		m := tupleVarRe.FindString(lines[i])
+		if m == "" {
+			continue
+		}
+		name := strings.TrimRight(m, " \t=")
+
+		var values []string
+		i, values = varValues(lines, i, m)
+		res[name] = append(res[name], values...)
+	}
+
+	return res
+}
+
+// DistfileSites returns modules2tuple-generated MASTER_SITES additions for
+// tuples fetched as regular distfiles in Makefile contents.
+func DistfileSites(makefile string) []string {
+	var res []string
+
+	lines := strings.Split(makefile, "\n")
+	for i := 0; i < len(lines); i++ {
+		m := masterSitesVarRe.FindString(lines[i])
+		if m == "" {
+			continue
+		}
+
+		var values []string
+		i, values = varValues(lines, i, m)
+		for _, v := range values {
+			if distfileValueRe.MatchString(v) {
+				res = append(res, v)
+			}
+		}
+	}
+
+	return res
+}
+
+// PostExtract returns modules2tuple-generated lines of post-extract recipe
+// in Makefile contents.
+func PostExtract(makefile string) []string {
+	var res []string
+
+	lines := strings.Split(makefile, "\n")
+	for i := 0; i < len(lines); i++ {
+		if !postExtractRe.MatchString(lines[i]) {
+			continue
+		}
+		for i++; i < len(lines) && strings.HasPrefix(lines[i], "\t"); i++ {
+			if recipeRe.MatchString(lines[i]) {
+				res = append(res, lines[i])
+			}
+		}
+		break
+	}
+
+	return res
+}
+
+// varValues returns values of the variable assigned at lines[i] with the
+// assignment prefix m, following line continuations. It also returns index
+// of the last line of the assignment.
+func varValues(lines []string, i int, m string) (int, []string) {
+	value := strings.TrimPrefix(lines[i], m)
+	for strings.HasSuffix(value, "\\") && i < len(lines)-1 {
+		i++
+		value = strings.TrimSuffix(value, "\\") + " " + lines[i]
+	}
+	return i, strings.Fields(strings.TrimSuffix(value, "\\"))
+}
+
+// split splits generated modules2tuple output into tuples with comments and post-extract recipe.
+func split(generated string) ([]string, []string) {
+	var tuples, recipe []string
+
+	lines := strings.Split(strings.Trim(generated, "\n"), "\n")
+	for i, line := range lines {
+		if postExtractRe.MatchString(line) {
+			recipe = lines[i+1:]
+			break
+		}
+		tuples = append(tuples, line)
+	}
+	for len(tuples) > 0 && tuples[len(tuples)-1] == "" {
+		tuples = tuples[:len(tuples)-1]
+	}
+
+	return tuples, recipe
+}
+
+// skipTuples returns index of the first line after G{H,L}_TUPLE variables
+// starting at lines[i] and any comments or other tuple variables immediately following them.
+func skipTuples(lines []string, i int) int {
+	for i < len(lines) {
+		switch {
+		case isTupleVar(lines, i):
+			for i < len(lines) && strings.HasSuffix(lines[i], "\\") {
+				i++
+			}
+			i++
+		case commentRe.MatchString(lines[i]):
+			i++
+		default:
+			return i
+		}
+		// look past blank lines
+		j := i
+		for j < len(lines) && lines[j] == "" {
+			j++
+		}
+		if j == len(lines) || !(isTupleVar(lines, j) || commentRe.MatchString(lines[j])) {
+			return i
+		}
+		i = j
+	}
+	return i
+}
+
+// isTupleVar reports whether lines[i] starts G{H,L}_TUPLE variable or
+// modules2tuple-generated MASTER_SITES+= or DISTFILES+= assignment.
+func isTupleVar(lines []string, i int) bool {
+	if tupleVarRe.MatchString(lines[i]) {
+		return true
+	}
+	if !distfileVarRe.MatchString(lines[i]) {
+		return false
+	}
+	for ; i < len(lines); i++ {
+		if distfileValueRe.MatchString(lines[i]) {
+			return true
+		}
+		if !strings.HasSuffix(lines[i], "\\") {
+			break
+		}
+	}
+	return false
+}
+
+// updateRecipe replaces generated lines in the recipe starting at lines[i] with recipe.
+// It returns index of the first line after the recipe and the updated recipe.
+func updateRecipe(lines []string, i int, recipe []string) (int, []string) {
+	var (
+		res  []string
+		done bool
+	)
+
+	for ; i < len(lines) && strings.HasPrefix(lines[i], "\t"); i++ {
+		if recipeRe.MatchString(lines[i]) {
+			if !done {
+				res = append(res, recipe...)
+				done = true
+			}
+			continue
+		}
+		res = append(res, lines[i])
+	}
+	if !done {
+		res = append(append([]string{}, recipe...), res...)
+	}
+
+	return i, res
+}
+
+// dropBlank removes extra blank line left in res after removing lines preceding lines[i].
+func dropBlank(res, lines []string, i int) []string {
+	if len(res) > 0 && res[len(res)-1] == "" && (i == len(lines) || lines[i] == "") {
+		return res[:len(res)-1]
+	}
+	return res
+}
+
+// insertionPoint returns index of the line after the first matching assignment in lines.
+func insertionPoint(lines []string) int {
+	for _, re := range insertAfterRe {
+		for i := 0; i < len(lines); i++ {
+			if re.MatchString(lines[i]) {
+				for i < len(lines)-1 && strings.HasSuffix(lines[i], "\\") {
+					i++
+				}
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+func insert(lines []string, i int, v []string) []string {
+	res := make([]string, 0, len(lines)+len(v))
+	res = append(res, lines[:i]...)
+	res = append(res, v...)
+	return append(res, lines[i:]...)
+}
@@ -0,0 +1,279 @@
+package makefile
+
+import (
+	"testing"
+)
+
+const generated = `GH_TUPLE=	\
+		golang:sys:v0.1.0:golang_sys/vendor/golang.org/x/sys \
+		pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors \
+		ugorji:go:v1.1.7:ugorji_go/vendor/github.com/ugorji/go \
+		yaml:yaml:v3.0.1:yaml_yaml/vendor/gopkg.in/yaml.v3
+
+GL_TUPLE=	gitlab-org:labkit:0c3fc7cdd57c57da5ab474aa72b6640d2bdc9ebb:gitlab_org_labkit/vendor/gitlab.com/gitlab-org/labkit
+
+post-extract:
+	@${MKDIR} ${WRKSRC}/vendor/github.com/json-iterator
+	@${RLN} ${WRKSRC_ugorji_go} ${WRKSRC}/vendor/github.com/json-iterator/go`
+
+func TestUpdate(t *testing.T) {
+	given := `PORTNAME=	example
+DISTVERSION=	1.0.0
+
+USES=		go:modules
+USE_GITHUB=	nodefault
+GH_TUPLE=	golang:sys:v0.0.1:golang_sys/vendor/golang.org/x/sys \
+		pkg:errors:v0.8.0:pkg_errors/vendor/github.com/pkg/errors
+
+		# Mirrors for the following packages are not currently known, please look them up and handle these tuples manually:
+		#	::v1.0.0:group_name/vendor/another.vanity_url.org/account/project (from another.vanity_url.org/account/project@v1.0.0)
+
+PLIST_FILES=	bin/example
+
+post-extract:
+	@${RM} -r ${WRKSRC}/vendor/github.com/old/module
+	@${RLN} ${WRKSRC_old_module} ${WRKSRC}/vendor/github.com/old/module
+	@${REINPLACE_CMD} -e 's|/usr/local|${PREFIX}|' ${WRKSRC}/main.go
+
+.include <bsd.port.mk>
+`
+
+	expected := `PORTNAME=	example
+DISTVERSION=	1.0.0
+
+USES=		go:modules
+USE_GITHUB=	nodefault
+GH_TUPLE=	\
+		golang:sys:v0.1.0:golang_sys/vendor/golang.org/x/sys \
+		pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors \
+		ugorji:go:v1.1.7:ugorji_go/vendor/github.com/ugorji/go \
+		yaml:yaml:v3.0.1:yaml_yaml/vendor/gopkg.in/yaml.v3
+
+GL_TUPLE=	gitlab-org:labkit:0c3fc7cdd57c57da5ab474aa72b6640d2bdc9ebb:gitlab_org_labkit/vendor/gitlab.com/gitlab-org/labkit
+
+PLIST_FILES=	bin/example
+
+post-extract:
+	@${MKDIR} ${WRKSRC}/vendor/github.com/json-iterator
+	@${RLN} ${WRKSRC_ugorji_go} ${WRKSRC}/vendor/github.com/json-iterator/go
+	@${REINPLACE_CMD} -e 's|/usr/local|${PREFIX}|' ${WRKSRC}/main.go
+
+.include <bsd.port.mk>
+`
+
+	out, err := Update(given, generated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != expected {
+		t.Errorf("expected output\n%s\n, got\n%s\n%s", expected, out, Diff("Makefile", expected, out))
+	}
+}
+
+func TestUpdateInsert(t *testing.T) {
+	given := `PORTNAME=	example
+DISTVERSION=	1.0.0
+
+USES=		go:modules
+USE_GITHUB=	yes
+
+PLIST_FILES=	bin/example
+
+.include <bsd.port.mk>
+`
+
+	expected := `PORTNAME=	example
+DISTVERSION=	1.0.0
+
+USES=		go:modules
+USE_GITHUB=	yes
+
+GH_TUPLE=	\
+		golang:sys:v0.1.0:golang_sys/vendor/golang.org/x/sys \
+		pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors \
+		ugorji:go:v1.1.7:ugorji_go/vendor/github.com/ugorji/go \
+		yaml:yaml:v3.0.1:yaml_yaml/vendor/gopkg.in/yaml.v3
+
+GL_TUPLE=	gitlab-org:labkit:0c3fc7cdd57c57da5ab474aa72b6640d2bdc9ebb:gitlab_org_labkit/vendor/gitlab.com/gitlab-org/labkit
+
+PLIST_FILES=	bin/example
+
+post-extract:
+	@${MKDIR} ${WRKSRC}/vendor/github.com/json-iterator
+	@${RLN} ${WRKSRC_ugorji_go} ${WRKSRC}/vendor/github.com/json-iterator/go
+
+.include <bsd.port.mk>
+`
+
+	out, err := Update(given, generated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != expected {
+		t.Errorf("expected output\n%s\n, got\n%s\n%s", expected, out, Diff("Makefile", expected, out))
+	}
+}
+
+func TestUpdateRemovePostExtract(t *testing.T) {
+	given := `USES=		go:modules
+GH_TUPLE=	pkg:errors:v0.8.0:pkg_errors/vendor/github.com/pkg/errors
+
+post-extract:
+	@${RLN} ${WRKSRC_old_module} ${WRKSRC}/vendor/github.com/old/module
+
+.include <bsd.port.mk>
+`
+
+	expected := `USES=		go:modules
+GH_TUPLE=	pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors
+
+.include <bsd.port.mk>
+`
+
+	out, err := Update(given, "GH_TUPLE=\tpkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != expected {
+		t.Errorf("expected output\n%s\n, got\n%s\n%s", expected, out, Diff("Makefile", expected, out))
+	}
+}
+
+func TestUpdateDistfiles(t *testing.T) {
+	given := `USES=		go:modules
+GH_TUPLE=	pkg:errors:v0.8.0:pkg_errors/vendor/github.com/pkg/errors
+
+MASTER_SITES+=	https://git.sr.ht/~sircmpwn/getopt/archive/v0.9.0.tar.gz?dummy=/:sircmpwn_getopt
+DISTFILES+=	sircmpwn-getopt-v0.9.0_SH0.tar.gz:sircmpwn_getopt
+
+post-extract:
+	@${MKDIR} ${WRKSRC}/vendor/git.sr.ht/~sircmpwn
+	@${MV} ${WRKDIR}/getopt-v0.9.0 ${WRKSRC}/vendor/git.sr.ht/~sircmpwn/getopt
+	@${FIND} ${WRKSRC}/vendor/github.com/pkg/errors -mindepth 2 -type f -delete
+	@${FIND} ${WRKSRC}/vendor/github.com/pkg/errors -mindepth 1 -type d -empty -delete
+
+.include <bsd.port.mk>
+`
+
+	expected := `USES=		go:modules
+GH_TUPLE=	pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors
+
+MASTER_SITES+=	https://git.sr.ht/~sircmpwn/getopt/archive/v1.0.0.tar.gz?dummy=/:sircmpwn_getopt
+DISTFILES+=	sircmpwn-getopt-v1.0.0_SH0.tar.gz:sircmpwn_getopt
+
+post-extract:
+	@${MKDIR} ${WRKSRC}/vendor/git.sr.ht/~sircmpwn
+	@${MV} ${WRKDIR}/getopt-v1.0.0 ${WRKSRC}/vendor/git.sr.ht/~sircmpwn/getopt
+
+.include <bsd.port.mk>
+`
+
+	generated := `GH_TUPLE=	pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors
+
+MASTER_SITES+=	https://git.sr.ht/~sircmpwn/getopt/archive/v1.0.0.tar.gz?dummy=/:sircmpwn_getopt
+DISTFILES+=	sircmpwn-getopt-v1.0.0_SH0.tar.gz:sircmpwn_getopt
+
+post-extract:
+	@${MKDIR} ${WRKSRC}/vendor/git.sr.ht/~sircmpwn
+	@${MV} ${WRKDIR}/getopt-v1.0.0 ${WRKSRC}/vendor/git.sr.ht/~sircmpwn/getopt`
+
+	out, err := Update(given, generated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != expected {
+		t.Errorf("expected output\n%s\n, got\n%s\n%s", expected, out, Diff("Makefile", expected, out))
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj"
+	new := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk"
+
+	expected := `--- Makefile
++++ Makefile
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+
+	out := Diff("Makefile", old, new)
+	if out != expected {
+		t.Errorf("expected diff\n%s\n, got\n%s\n", expected, out)
+	}
+}
+
+func TestTupleVars(t *testing.T) {
+	given := `USES=		go:modules
+GH_TUPLE=	\
+		golang:sys:v0.1.0:golang_sys/vendor/golang.org/x/sys \
+		pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors
+GL_TUPLE=	gitlab-org:labkit:0c3fc7cdd57c57da5ab474aa72b6640d2bdc9ebb:gitlab_org_labkit/vendor/gitlab.com/gitlab-org/labkit
+
+.include <bsd.port.mk>`
+
+	vars := TupleVars(given)
+	if len(vars) != 2 {
+		t.Fatalf("expected 2 variables, got %d", len(vars))
+	}
+	gh := vars["GH_TUPLE"]
+	if len(gh) != 2 || gh[0] != "golang:sys:v0.1.0:golang_sys/vendor/golang.org/x/sys" || gh[1] != "pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors" {
+		t.Errorf("unexpected GH_TUPLE entries: %q", gh)
+	}
+	gl := vars["GL_TUPLE"]
+	if len(gl) != 1 || gl[0] != "gitlab-org:labkit:0c3fc7cdd57c57da5ab474aa72b6640d2bdc9ebb:gitlab_org_labkit/vendor/gitlab.com/gitlab-org/labkit" {
+		t.Errorf("unexpected GL_TUPLE entries: %q", gl)
+	}
+}
+
+func TestDistfileSitesAndPostExtract(t *testing.T) {
+	given := `USES=		go:modules
+MASTER_SITES+=	https://example.org/
+GH_TUPLE=	pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors
+
+MASTER_SITES+=	\
+		https://codeberg.org/gruf/go-bytes/archive/v1.0.2.tar.gz?dummy=/:gruf_go_bytes \
+		https://git.sr.ht/~sircmpwn/getopt/archive/v1.0.0.tar.gz?dummy=/:sircmpwn_getopt
+DISTFILES+=	\
+		gruf-go-bytes-v1.0.2_GT0.tar.gz:gruf_go_bytes \
+		sircmpwn-getopt-v1.0.0_SH0.tar.gz:sircmpwn_getopt
+
+post-extract:
+	@${MKDIR} ${WRKSRC}/vendor/codeberg.org/gruf
+	@${MV} ${WRKDIR}/go-bytes ${WRKSRC}/vendor/codeberg.org/gruf/go-bytes
+	@${REINPLACE_CMD} -e 's|foo|bar|' ${WRKSRC}/main.go
+	@${MKDIR} ${WRKSRC}/vendor/git.sr.ht/~sircmpwn
+	@${MV} ${WRKDIR}/getopt-v1.0.0 ${WRKSRC}/vendor/git.sr.ht/~sircmpwn/getopt
+
+.include <bsd.port.mk>`
+
+	sites := DistfileSites(given)
+	if len(sites) != 2 || sites[0] != "https://codeberg.org/gruf/go-bytes/archive/v1.0.2.tar.gz?dummy=/:gruf_go_bytes" || sites[1] != "https://git.sr.ht/~sircmpwn/getopt/archive/v1.0.0.tar.gz?dummy=/:sircmpwn_getopt" {
+		t.Errorf("unexpected MASTER_SITES entries: %q", sites)
+	}
+
+	recipe := PostExtract(given)
+	expected := []string{
+		"\t@${MKDIR} ${WRKSRC}/vendor/codeberg.org/gruf",
+		"\t@${MV} ${WRKDIR}/go-bytes ${WRKSRC}/vendor/codeberg.org/gruf/go-bytes",
+		"\t@${MKDIR} ${WRKSRC}/vendor/git.sr.ht/~sircmpwn",
+		"\t@${MV} ${WRKDIR}/getopt-v1.0.0 ${WRKSRC}/vendor/git.sr.ht/~sircmpwn/getopt",
+	}
+	if len(recipe) != len(expected) {
+		t.Fatalf("expected post-extract lines\n%q\n, got\n%q\n", expected, recipe)
+	}
+	for i := range expected {
+		if recipe[i] != expected[i] {
+			t.Errorf("(%d) expected post-extract line %q, got %q", i, expected[i], recipe[i])
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dmgk/modules2tuple/v2/tuple"
+	"golang.org/x/mod/modfile"
+)
+
+// LoadGoMod parses tuples from go.mod at path. If go.sum exists next to go.mod,
+// it's used to skip modules that don't contribute any source code to the build.
+func LoadGoMod(ctx context.Context, path string, opts *tuple.Options) (*Result, error) {
+	mod, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer mod.Close()
+
+	sum, err := os.Open(filepath.Join(filepath.Dir(path), "go.sum"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		return ReadGoMod(ctx, mod, nil, opts)
+	}
+	defer sum.Close()
+
+	return ReadGoMod(ctx, mod, sum, opts)
+}
+
+// ReadGoMod parses tuples from go.mod and go.sum contents provided as io.Reader.
+// sum may be nil, in which case all required modules are used.
+func ReadGoMod(ctx context.Context, mod, sum io.Reader, opts *tuple.Options) (*Result, error) {
+	f, err := parseGoMod(mod)
+	if err != nil {
+		return nil, err
+	}
+
+	var s goSum
+	if sum != nil {
+		s = goSum{}
+		if err := readGoSum(sum, s); err != nil {
+			return nil, fmt.Errorf("go.sum: %v", err)
+		}
+	}
+
+	res, err := resolve(ctx, explicitSpecs(f.specs(s, opts)), opts)
+	if err != nil {
+		return nil, err
+	}
+	if !f.prunedGraph() {
+		res.AddError(fmt.Errorf("go.mod declares go version %q, indirect dependencies may be missing, consider running \"go mod tidy -go=1.17\" first", f.goVersion))
+	}
+	return res, nil
+}
+
+// goMod is a parsed go.mod file.
+type goMod struct {
+	module    string
+	goVersion string
+	require   []modVersion
+	replace   []modReplace
+	exclude   []modVersion
+}
+
+func parseGoMod(r io.Reader) (*goMod, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("go.mod: %v", err)
+	}
+	f, err := modfile.Parse("go.mod", data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &goMod{}
+	if f.Module != nil {
+		res.module = f.Module.Mod.Path
+	}
+	if f.Go != nil {
+		res.goVersion = f.Go.Version
+	}
+	for _, r := range f.Require {
+		res.require = append(res.require, modVersion{r.Mod.Path, r.Mod.Version})
+	}
+	for _, e := range f.Exclude {
+		res.exclude = append(res.exclude, modVersion{e.Mod.Path, e.Mod.Version})
+	}
+	res.replace = modReplaces(f.Replace)
+
+	return res, nil
+}
+
+// specs returns modules.txt-style package specs for all required modules.
+// If sum is not nil, modules without source checksums in go.sum are skipped.
+func (f *goMod) specs(sum goSum, opts *tuple.Options) []string {
+	var res []string
+
+	for _, m := range f.require {
+		if f.isExcluded(m) {
+			opts.Logf("[goMod.specs] skipping excluded %s\n", m)
+			continue
+		}
+		spec, repl := replaceSpec(f.replace, m)
+		if sum != nil && !isFilesystemPath(repl.path) && !sum.has(repl) {
+			opts.Logf("[goMod.specs] skipping %s, not in go.sum\n", repl)
+			continue
+		}
+		res = append(res, spec)
+	}
+
+	return res
+}
+
+func (f *goMod) isExcluded(m modVersion) bool {
+	for _, e := range f.exclude {
+		if e == m {
+			return true
+		}
+	}
+	return false
+}
+
+// prunedGraph returns true if go.mod lists all indirect dependencies.
+// https://go.dev/ref/mod#graph-pruning
+func (f *goMod) prunedGraph() bool {
+	if f.goVersion == "" {
+		return false
+	}
+	parts := strings.SplitN(f.goVersion, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	return major > 1 || minor >= 17
+}
+
+func isFilesystemPath(s string) bool {
+	return s != "" && (s[0] == '.' || s[0] == '/')
+}
@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestReadGoMod(t *testing.T) {
+	givenMod := `
+module github.com/account/project
+
+go 1.17
+
+require (
+	github.com/karrick/godirwalk v1.10.12
+	github.com/rogpeppe/go-internal v1.3.0 // indirect
+	github.com/spf13/cobra v0.0.0-20180412120829-615425954c3b
+	github.com/hashicorp/vault/api v1.0.5-0.20200215224050-f6547fa8e820
+	gopkg.in/yaml.v2 v2.2.4
+	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
+)
+
+require "github.com/pkg/errors" v0.9.1
+
+exclude github.com/pkg/errors v0.9.1
+
+replace (
+	github.com/spf13/cobra => github.com/rsteube/cobra v0.0.1-zsh-completion-custom
+	github.com/hashicorp/vault/api => ./api
+	gopkg.in/yaml.v2 v2.2.2 => github.com/go-yaml/yaml v2.1.0+incompatible
+)`
+
+	givenSum := `
+github.com/karrick/godirwalk v1.10.12 h1:BqUm+LuJcXjGv1d2mj3gBiQyrQ57a0rYoAmhvJQ7RDU=
+github.com/karrick/godirwalk v1.10.12/go.mod h1:RoGL9dQei4vP9ilrpETWE8CLOZ1kiN0LhBygSwrAsHA=
+github.com/rogpeppe/go-internal v1.3.0 h1:RR9dF3JtopPvtkroDZuVD7qquD0bnHlKSqaQhgwt8yk=
+github.com/rogpeppe/go-internal v1.3.0/go.mod h1:M8bDsm7K2OlrFYOpmOWEs/qY81heoFRclV5y23lUDJ4=
+github.com/rsteube/cobra v0.0.1-zsh-completion-custom h1:xWmzgVcEWgFrl8tTHqG6bRZcDt6rGVQeNGnLD6Cwf4w=
+gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15/go.mod h1:Co6ibVJAznAaIkqp8huTwlJQCZ016jof/cbN4VW5Yz0=
+gopkg.in/yaml.v2 v2.2.4 h1:/eiJrUcujPVeJ3xlSWaiNi3uSVmDGBK1pDHUHAnao1I=
+`
+
+	expected := `GH_TUPLE=	\
+		go-yaml:yaml:v2.2.4:go_yaml_yaml/vendor/gopkg.in/yaml.v2 \
+		hashicorp:vault:f6547fa8e820:hashicorp_vault_api/github.com/hashicorp/vault/api \
+		karrick:godirwalk:v1.10.12:karrick_godirwalk/vendor/github.com/karrick/godirwalk \
+		rogpeppe:go-internal:v1.3.0:rogpeppe_go_internal/vendor/github.com/rogpeppe/go-internal \
+		rsteube:cobra:v0.0.1-zsh-completion-custom:rsteube_cobra/vendor/github.com/spf13/cobra
+
+post-extract:
+	@${RM} -r ${WRKSRC}/api
+	@${RLN} ${WRKSRC_hashicorp_vault_api}/api ${WRKSRC}/api`
+
+	res, err := ReadGoMod(context.Background(), strings.NewReader(givenMod), strings.NewReader(givenSum), offlineOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := res.String()
+	if out != expected {
+		t.Errorf("expected output\n%q\n, got\n%q\n", expected, out)
+	}
+}
+
+func TestReadGoModWithoutGoSum(t *testing.T) {
+	given := `module github.com/account/project
+
+go 1.16
+
+require github.com/pkg/errors v0.9.1`
+
+	expected := `GH_TUPLE=	pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors
+
+		# Errors found during processing:
+		#	go.mod declares go version "1.16", indirect dependencies may be missing, consider running "go mod tidy -go=1.17" first`
+
+	res, err := ReadGoMod(context.Background(), strings.NewReader(given), nil, offlineOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := res.String()
+	if out != expected {
+		t.Errorf("expected output\n%q\n, got\n%q\n", expected, out)
+	}
+}
+
+func TestReadGoModDirectives(t *testing.T) {
+	given := `module "github.com/account/project"
+
+go 1.21
+
+toolchain go1.22.1
+
+godebug (
+	default=go1.21
+	panicnil=1
+)
+
+retract [v1.0.0, v1.0.5] // broken releases
+
+require (
+	"github.com/pkg/errors" v0.9.1 // indirect
+)`
+
+	expected := `GH_TUPLE=	pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors`
+
+	res, err := ReadGoMod(context.Background(), strings.NewReader(given), nil, offlineOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := res.String()
+	if out != expected {
+		t.Errorf("expected output\n%q\n, got\n%q\n", expected, out)
+	}
+}
+
+func TestReadGoModFail(t *testing.T) {
+	given := "module github.com/account/project\n\nrequire github.com/pkg/errors"
+
+	_, err := ReadGoMod(context.Background(), strings.NewReader(given), nil, offlineOptions)
+	if err == nil || !strings.HasPrefix(err.Error(), "go.mod:3:") {
+		t.Errorf("expected go.mod:3 error, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/dmgk/modules2tuple/v2/tuple"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// LoadGoWork parses tuples from go.work at path and go.mod files of all workspace modules.
+func LoadGoWork(ctx context.Context, p string, opts *tuple.Options) (*Result, error) {
+	return ReadGoWork(ctx, os.DirFS(filepath.Dir(p)), filepath.Base(p), opts)
+}
+
+// ReadGoWork parses tuples from go.work file name in fsys. Workspace modules are
+// looked up in fsys relative to the go.work location and their requirements are
+// merged, keeping the highest required version of each module. Workspace modules
+// required by other workspace modules are symlinked from their local directories.
+func ReadGoWork(ctx context.Context, fsys fs.FS, name string, opts *tuple.Options) (*Result, error) {
+	w, err := parseGoWork(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resolve(ctx, explicitSpecs(w.specs(opts)), opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range w.modules {
+		if !m.prunedGraph() {
+			res.AddError(fmt.Errorf("%s: go.mod declares go version %q, indirect dependencies may be missing, consider running \"go mod tidy -go=1.17\" first", m.dir, m.goVersion))
+		}
+	}
+	return res, nil
+}
+
+// goWork is a parsed go.work file along with all workspace modules.
+type goWork struct {
+	modules []*workModule
+	replace []modReplace
+	sum     goSum
+}
+
+// workModule is a workspace module.
+type workModule struct {
+	*goMod
+	dir string // module directory, relative to go.work
+}
+
+func parseGoWork(fsys fs.FS, name string) (*goWork, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	wf, err := modfile.ParseWork(name, data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	root := path.Dir(name)
+	res := &goWork{replace: modReplaces(wf.Replace)}
+
+	for _, u := range wf.Use {
+		dir := path.Join(root, u.Path)
+		if !fs.ValidPath(dir) {
+			return nil, fmt.Errorf("%s:%d: workspace module %q is outside of the workspace", name, u.Syntax.Start.Line, u.Path)
+		}
+		m, err := loadWorkModule(fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+		res.modules = append(res.modules, m)
+	}
+
+	if len(res.modules) == 0 {
+		return nil, fmt.Errorf("%s: no workspace modules", name)
+	}
+
+	// merge all available checksums, skip go.sum filtering if there are none
+	sum := goSum{}
+	sumFiles := []string{path.Join(root, "go.work.sum")}
+	for _, m := range res.modules {
+		sumFiles = append(sumFiles, path.Join(m.dir, "go.sum"))
+	}
+	for _, sf := range sumFiles {
+		if err := loadGoSum(fsys, sf, sum); err != nil {
+			return nil, err
+		}
+	}
+	if len(sum) > 0 {
+		res.sum = sum
+	}
+
+	return res, nil
+}
+
+func loadWorkModule(fsys fs.FS, dir string) (*workModule, error) {
+	f, err := fsys.Open(path.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := parseGoMod(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", dir, err)
+	}
+
+	// filesystem replacements are relative to the module directory, rebase them onto the workspace root
+	for i, r := range m.replace {
+		if isFilesystemPath(r.new.path) && !path.IsAbs(r.new.path) {
+			m.replace[i].new.path = relPath(path.Join(dir, r.new.path))
+		}
+	}
+
+	return &workModule{m, dir}, nil
+}
+
+func loadGoSum(fsys fs.FS, name string, sum goSum) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	if err := readGoSum(f, sum); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// specs returns modules.txt-style package specs for the union of requirements of all workspace modules.
+func (w *goWork) specs(opts *tuple.Options) []string {
+	local := map[string]string{}
+	for _, m := range w.modules {
+		local[m.module] = relPath(m.dir)
+	}
+
+	required := map[string]modVersion{}
+	for _, m := range w.modules {
+		for _, r := range m.require {
+			if w.isExcluded(r) {
+				opts.Logf("[goWork.specs] skipping excluded %s\n", r)
+				continue
+			}
+			if prev, ok := required[r.path]; ok && semver.Compare(prev.version, r.version) >= 0 {
+				continue
+			}
+			required[r.path] = r
+		}
+	}
+
+	paths := make([]string, 0, len(required))
+	for p := range required {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var res []string
+	for _, p := range paths {
+		m := required[p]
+		if dir, ok := local[p]; ok {
+			// workspace module, get it and symlink to the local directory
+			res = append(res, fmt.Sprintf("%s => %s", m, dir))
+			continue
+		}
+		spec, repl := w.replaceSpec(m)
+		if w.sum != nil && !isFilesystemPath(repl.path) && !w.sum.has(repl) {
+			opts.Logf("[goWork.specs] skipping %s, not in go.sum\n", repl)
+			continue
+		}
+		res = append(res, spec)
+	}
+
+	return res
+}
+
+// replaceSpec returns a spec for module m. Workspace replacements override
+// the ones from workspace modules.
+func (w *goWork) replaceSpec(m modVersion) (string, modVersion) {
+	if _, ok := findReplace(w.replace, m); ok {
+		return replaceSpec(w.replace, m)
+	}
+	for _, wm := range w.modules {
+		if _, ok := findReplace(wm.replace, m); ok {
+			return replaceSpec(wm.replace, m)
+		}
+	}
+	return m.String(), m
+}
+
+func (w *goWork) isExcluded(m modVersion) bool {
+	for _, wm := range w.modules {
+		if wm.isExcluded(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// relPath returns slash-separated path p in the "./path" form expected by filesystem replacements.
+func relPath(p string) string {
+	p = path.Clean(p)
+	if p == "." {
+		return p
+	}
+	return "./" + p
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadGoWork(t *testing.T) {
+	given := fstest.MapFS{
+		"go.work": {Data: []byte(`
+go 1.18
+
+use (
+	.
+	./api
+)
+
+replace github.com/spf13/cobra => github.com/rsteube/cobra v0.0.1-zsh-completion-custom`)},
+		"go.mod": {Data: []byte(`
+module github.com/account/project
+
+go 1.18
+
+require (
+	github.com/account/project/api v0.1.0
+	github.com/pkg/errors v0.8.1
+	github.com/spf13/cobra v1.0.0
+)
+
+replace github.com/account/project/api => ./api`)},
+		"api/go.mod": {Data: []byte(`
+module github.com/account/project/api
+
+go 1.18
+
+require (
+	github.com/pkg/errors v0.9.1
+	github.com/karrick/godirwalk v1.10.12
+	github.com/spf13/cobra v0.5.0
+)
+
+replace github.com/karrick/godirwalk => ../third_party/godirwalk`)},
+	}
+
+	expected := `GH_TUPLE=	\
+		account:project:v0.1.0:account_project_api/github.com/account/project/api \
+		karrick:godirwalk:v1.10.12:karrick_godirwalk/github.com/karrick/godirwalk \
+		pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors \
+		rsteube:cobra:v0.0.1-zsh-completion-custom:rsteube_cobra/vendor/github.com/spf13/cobra
+
+post-extract:
+	@${RM} -r ${WRKSRC}/api
+	@${RLN} ${WRKSRC_account_project_api}/api ${WRKSRC}/api
+	@${RLN} ${WRKSRC_karrick_godirwalk} ${WRKSRC}/third_party/godirwalk`
+
+	res, err := ReadGoWork(context.Background(), given, "go.work", offlineOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := res.String()
+	if out != expected {
+		t.Errorf("expected output\n%q\n, got\n%q\n", expected, out)
+	}
+}
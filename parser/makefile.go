@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"io"
+	"os"
+
+	"github.com/dmgk/modules2tuple/v2/makefile"
+	"github.com/dmgk/modules2tuple/v2/tuple"
+)
+
+// LoadMakefile parses tuples from the port Makefile at path.
+func LoadMakefile(path string) (*Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadMakefile(f)
+}
+
+// ReadMakefile parses tuples from G{H,L}_TUPLE variables, MASTER_SITES additions
+// and post-extract target of the port Makefile contents provided as io.Reader.
+// Tuples are used as-is, without resolving or fixing them.
+func ReadMakefile(r io.Reader) (*Result, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s := string(b)
+	tuples, errs := tuple.ParseMakefile(makefile.TupleVars(s), makefile.DistfileSites(s), makefile.PostExtract(s))
+
+	res := &Result{}
+	for _, t := range tuples {
+		res.AddTuple(t)
+	}
+	for _, err := range errs {
+		res.AddError(err)
+	}
+
+	return res, nil
+}
+
+// Diff compares tuples of old and new results, including the ones with unknown mirrors.
+func Diff(old, new *Result) (tuple.Changes, error) {
+	return tuple.Diff(old.all(), new.all())
+}
+
+// all returns all tuples, including unresolved ones.
+func (r *Result) all() tuple.Slice {
+	return append(append(tuple.Slice{}, r.tuples...), r.unresolved...)
+}
@@ -0,0 +1,187 @@
+package parser
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dmgk/modules2tuple/v2/tuple"
+)
+
+func TestDiff(t *testing.T) {
+	givenMakefile := `PORTNAME=	example
+
+USES=		go:modules
+USE_GITHUB=	nodefault
+GH_TUPLE=	\
+		golang:sys:v0.1.0:golang_sys/vendor/golang.org/x/sys \
+		karrick:godirwalk:v1.10.12:karrick_godirwalk/vendor/github.com/karrick/godirwalk \
+		pkg:errors:v0.8.0:pkg_errors/vendor/github.com/pkg/errors \
+		rogpeppe:go-internal:e0a95dfd547c:rogpeppe_go_internal/vendor/github.com/rogpeppe/go-internal
+GL_TUPLE=	gitlab-org:labkit:0c3fc7cdd57c57da5ab474aa72b6640d2bdc9ebb:gitlab_org_labkit/vendor/gitlab.com/gitlab-org/labkit
+
+.include <bsd.port.mk>`
+
+	givenModules := `
+# github.com/karrick/godirwalk v1.10.12
+# github.com/pkg/errors v0.9.1
+# github.com/rogpeppe/go-internal v1.3.0
+# github.com/ugorji/go v0.0.0-20190204201341-e444a5086c43
+# gitlab.com/gitlab-org/labkit v0.0.0-20190221122536-0c3fc7cdd57c
+# golang.org/x/sys v0.1.0 => golang.org/x/sys v0.0.0-20181001143604-e0a95dfd547c`
+
+	expected := `Added:
+	github.com/ugorji/go e444a5086c43 (ugorji:go:e444a5086c43:ugorji_go/vendor/github.com/ugorji/go)
+
+Version changed:
+	github.com/pkg/errors v0.8.0 -> v0.9.1
+
+Switched from tag to commit:
+	golang.org/x/sys v0.1.0 -> e0a95dfd547c
+
+Switched from commit to tag:
+	github.com/rogpeppe/go-internal e0a95dfd547c -> v1.3.0`
+
+	oldRes, err := ReadMakefile(strings.NewReader(givenMakefile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newRes, err := Read(context.Background(), strings.NewReader(givenModules), offlineOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := Diff(oldRes, newRes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := changes.String()
+	if out != expected {
+		t.Errorf("expected output\n%s\n, got\n%s\n", expected, out)
+	}
+}
+
+func TestDiffRoundTrip(t *testing.T) {
+	given := `
+# github.com/pkg/errors v0.9.1
+# gopkg.in/fsnotify.v1 v1.4.7
+# github.com/fsnotify/fsnotify v1.4.7
+# github.com/Sirupsen/logrus v1.4.2
+# github.com/sirupsen/logrus v1.4.2
+# github.com/account/project/api v0.1.0 => ./api
+# go.etcd.io/etcd/api/v3 v3.5.0
+# go.etcd.io/etcd/client/v3 v3.5.0
+# go.etcd.io/etcd/server/v3 v3.5.1
+# codeberg.org/gruf/go-bytes v1.0.2
+# git.sr.ht/~sircmpwn/getopt v1.0.0
+# bitbucket.org/account/project v0.0.0-20190221122536-0c3fc7cdd57c
+# gitlab.com/gitlab-org/labkit v0.0.0-20190221122536-0c3fc7cdd57c
+# golang.org/x/sys v0.1.0 => golang.org/x/sys v0.0.0-20181001143604-e0a95dfd547c`
+
+	// etcd modules are trimmed to the repository root using module origins,
+	// api/v3 is hidden as a part of client/v3 and server/v3 is symlinked
+	modcache := t.TempDir()
+	files := map[string]string{
+		"go.etcd.io/etcd/api/v3/@v/v3.5.0.info":    `{"Version":"v3.5.0","Origin":{"VCS":"git","URL":"https://github.com/etcd-io/etcd","Subdir":"api/v3","Ref":"refs/tags/api/v3/v3.5.0","Hash":"0123456789abcdef0123456789abcdef01234567"}}`,
+		"go.etcd.io/etcd/client/v3/@v/v3.5.0.info": `{"Version":"v3.5.0","Origin":{"VCS":"git","URL":"https://github.com/etcd-io/etcd","Subdir":"client/v3","Ref":"refs/tags/client/v3/v3.5.0","Hash":"0123456789abcdef0123456789abcdef01234567"}}`,
+		"go.etcd.io/etcd/server/v3/@v/v3.5.1.info": `{"Version":"v3.5.1","Origin":{"VCS":"git","URL":"https://github.com/etcd-io/etcd","Subdir":"server/v3","Ref":"refs/tags/server/v3/v3.5.1","Hash":"89abcdef0123456789abcdef0123456789abcdef"}}`,
+	}
+	for name, data := range files {
+		path := filepath.Join(modcache, "cache", "download", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := &tuple.Options{Offline: true, ModCache: modcache}
+
+	newRes, err := Read(context.Background(), strings.NewReader(given), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldRes, err := ReadMakefile(strings.NewReader("PORTNAME=\texample\n\n" + newRes.String() + "\n\n.include <bsd.port.mk>\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oldRes.HasErrors() {
+		t.Fatalf("expected Makefile to be parsed without errors, got\n%s", oldRes.String())
+	}
+	changes, err := Diff(oldRes, newRes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no changes, got\n%s", changes)
+	}
+}
+
+func TestDiffModules(t *testing.T) {
+	// etcd modules are trimmed to the repository root, api/v3 and client/v3
+	// share it at the old version
+	modcache := t.TempDir()
+	files := map[string]string{
+		"go.etcd.io/etcd/api/v3/@v/v3.5.0.info":    `{"Version":"v3.5.0","Origin":{"VCS":"git","URL":"https://github.com/etcd-io/etcd","Subdir":"api/v3","Ref":"refs/tags/api/v3/v3.5.0","Hash":"0123456789abcdef0123456789abcdef01234567"}}`,
+		"go.etcd.io/etcd/client/v3/@v/v3.5.0.info": `{"Version":"v3.5.0","Origin":{"VCS":"git","URL":"https://github.com/etcd-io/etcd","Subdir":"client/v3","Ref":"refs/tags/client/v3/v3.5.0","Hash":"0123456789abcdef0123456789abcdef01234567"}}`,
+		"go.etcd.io/etcd/client/v3/@v/v3.5.1.info": `{"Version":"v3.5.1","Origin":{"VCS":"git","URL":"https://github.com/etcd-io/etcd","Subdir":"client/v3","Ref":"refs/tags/client/v3/v3.5.1","Hash":"89abcdef0123456789abcdef0123456789abcdef"}}`,
+	}
+	for name, data := range files {
+		path := filepath.Join(modcache, "cache", "download", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := &tuple.Options{Offline: true, ModCache: modcache}
+
+	givenOld := `
+# go.etcd.io/etcd/api/v3 v3.5.0
+# go.etcd.io/etcd/client/v3 v3.5.0`
+	givenNew := `
+# go.etcd.io/etcd/api/v3 v3.5.0
+# go.etcd.io/etcd/client/v3 v3.5.1`
+
+	expected := `Version changed:
+	go.etcd.io/etcd/client/v3 0123456789ab -> 89abcdef0123`
+
+	oldRes, err := Read(context.Background(), strings.NewReader(givenOld), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newRes, err := Read(context.Background(), strings.NewReader(givenNew), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := Diff(oldRes, newRes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := changes.String()
+	if out != expected {
+		t.Errorf("expected output\n%s\n, got\n%s\n", expected, out)
+	}
+}
+
+func TestDiffFail(t *testing.T) {
+	givenMakefile := `GH_TUPLE=	\
+		pkg:errors:v0.8.0:pkg_errors/vendor/github.com/pkg/errors \
+		pkg:errors:v0.9.1:pkg_errors_1/vendor/github.com/pkg/errors`
+
+	oldRes, err := ReadMakefile(strings.NewReader(givenMakefile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newRes, err := Read(context.Background(), strings.NewReader("# github.com/pkg/errors v0.9.1"), offlineOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Diff(oldRes, newRes)
+	expected := "pkg:errors:v0.8.0:pkg_errors/vendor/github.com/pkg/errors and pkg:errors:v0.9.1:pkg_errors_1/vendor/github.com/pkg/errors both end up in ${WRKSRC}/vendor/github.com/pkg/errors"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// modVersion is a module path and version pair.
+type modVersion struct {
+	path    string
+	version string
+}
+
+func (m modVersion) String() string {
+	if m.version == "" {
+		return m.path
+	}
+	return m.path + " " + m.version
+}
+
+// modReplace is a replace directive.
+type modReplace struct {
+	old modVersion
+	new modVersion
+}
+
+// modReplaces converts go.mod or go.work replace directives.
+func modReplaces(replaces []*modfile.Replace) []modReplace {
+	var res []modReplace
+	for _, r := range replaces {
+		res = append(res, modReplace{
+			old: modVersion{r.Old.Path, r.Old.Version},
+			new: modVersion{r.New.Path, r.New.Version},
+		})
+	}
+	return res
+}
+
+// findReplace returns a replacement for module m. Version-specific replacements take
+// precedence over the ones applying to all versions of the module.
+func findReplace(replaces []modReplace, m modVersion) (modReplace, bool) {
+	var (
+		res   modReplace
+		found bool
+	)
+	for _, r := range replaces {
+		if r.old.path != m.path {
+			continue
+		}
+		if r.old.version == m.version {
+			return r, true
+		}
+		if r.old.version == "" {
+			res, found = r, true
+		}
+	}
+	return res, found
+}
+
+// replaceSpec returns modules.txt-style spec for module m, taking replacements into account.
+func replaceSpec(replaces []modReplace, m modVersion) (string, modVersion) {
+	r, ok := findReplace(replaces, m)
+	if !ok {
+		return m.String(), m
+	}
+	return fmt.Sprintf("%s => %s", m, r.new), r.new
+}
+
+// goSum is a set of "path version" modules that have source archive checksums in go.sum.
+type goSum map[modVersion]struct{}
+
+// readGoSum reads go.sum contents, skipping go.mod-only entries.
+func readGoSum(r io.Reader, sum goSum) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		f := strings.Fields(scanner.Text())
+		if len(f) == 0 {
+			continue
+		}
+		if len(f) != 3 {
+			return fmt.Errorf("unexpected go.sum line: %q", scanner.Text())
+		}
+		if strings.HasSuffix(f[1], "/go.mod") {
+			continue
+		}
+		sum[modVersion{f[0], f[1]}] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+func (s goSum) has(m modVersion) bool {
+	_, ok := s[m]
+	return ok
+}
@@ -3,6 +3,8 @@ package parser
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -15,62 +17,153 @@ import (
 )
 
 // Load parses tuples from vendor/modules.txt at path.
-func Load(path string) (*Result, error) {
+func Load(ctx context.Context, path string, opts *tuple.Options) (*Result, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 
-	return Read(f)
+	return Read(ctx, f, opts)
 }
 
 // Read parses tuples from modules.txt contents provided as io.Reader.
-func Read(r io.Reader) (*Result, error) {
+func Read(ctx context.Context, r io.Reader, opts *tuple.Options) (*Result, error) {
+	const (
+		specPrefix       = "# "
+		annotationPrefix = "## "
+	)
+
+	var specs []spec
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, annotationPrefix):
+			if len(specs) > 0 {
+				specs[len(specs)-1].annotate(strings.TrimPrefix(line, annotationPrefix))
+			}
+		case strings.HasPrefix(line, specPrefix):
+			specs = append(specs, spec{line: strings.TrimPrefix(line, specPrefix)})
+		case strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "#"):
+			if len(specs) > 0 {
+				specs[len(specs)-1].packages = append(specs[len(specs)-1].packages, strings.TrimSpace(line))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var skipped []string
+	if opts != nil && opts.SkipUnused {
+		used := specs[:0]
+		for _, s := range specs {
+			if len(s.packages) == 0 {
+				opts.Logf("[Read] skipping %s, no vendored packages\n", s.line)
+				skipped = append(skipped, s.line)
+				continue
+			}
+			used = append(used, s)
+		}
+		specs = used
+	}
+
+	res, err := resolve(ctx, specs, opts)
+	if err != nil {
+		return nil, err
+	}
+	res.skipped = skipped
+	return res, nil
+}
+
+// spec is a modules.txt package spec with its "## explicit; go 1.xx" annotations
+// and vendored packages listed under it.
+type spec struct {
+	line      string
+	explicit  bool
+	goVersion string
+	packages  []string
+}
+
+// annotate parses modules.txt module annotations, e.g. "explicit; go 1.17".
+func (s *spec) annotate(annotations string) {
+	for _, a := range strings.Split(annotations, ";") {
+		a = strings.TrimSpace(a)
+		switch {
+		case a == "explicit":
+			s.explicit = true
+		case strings.HasPrefix(a, "go "):
+			s.goVersion = strings.TrimSpace(strings.TrimPrefix(a, "go "))
+		}
+	}
+}
+
+// explicitSpecs returns specs for modules required explicitly in go.mod or go.work.
+func explicitSpecs(lines []string) []spec {
+	res := make([]spec, 0, len(lines))
+	for _, line := range lines {
+		res = append(res, spec{line: line, explicit: true})
+	}
+	return res
+}
+
+// resolve parses and fixes tuples from package specs concurrently.
+// Outstanding lookups are abandoned once ctx is done.
+func resolve(ctx context.Context, specs []spec, opts *tuple.Options) (*Result, error) {
 	ch := make(chan interface{})
 
 	go func() {
 		defer close(ch)
 
-		const specPrefix = "# "
-
-		scanner := bufio.NewScanner(r)
 		sem := make(chan int, runtime.NumCPU())
 		// sem := make(chan int, 1)
 		var wg sync.WaitGroup
 
-		for scanner.Scan() {
-			line := scanner.Text()
-			if strings.HasPrefix(line, specPrefix) {
-				sem <- 1
-				wg.Add(1)
-				go func() {
-					defer func() {
-						<-sem
-						wg.Done()
-					}()
-					t, err := tuple.Parse(strings.TrimPrefix(line, specPrefix))
-					if err != nil {
-						ch <- err
-						return
-					}
-					err = t.Fix()
-					if err != nil {
-						ch <- err
+		for _, spec := range specs {
+			spec := spec
+			if ctx.Err() != nil {
+				break
+			}
+			sem <- 1
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				t, err := tuple.Parse(ctx, spec.line, opts)
+				if err != nil {
+					if _, ok := err.(tuple.SourceError); ok && t != nil {
+						t.Annotate(spec.explicit, spec.goVersion)
+						ch <- unresolved{t, err}
 						return
 					}
-					ch <- t
-				}()
-			}
+					ch <- err
+					return
+				}
+				t.Annotate(spec.explicit, spec.goVersion)
+				t.SetPackages(spec.packages, opts)
+				err = t.Fix(ctx, opts)
+				if err != nil {
+					ch <- err
+					return
+				}
+				ch <- t
+			}()
 		}
 		wg.Wait()
 	}()
 
-	res := &Result{}
+	res := &Result{opts: opts}
 
 	for v := range ch {
 		if t, ok := v.(*tuple.Tuple); ok {
 			res.AddTuple(t)
+		} else if u, ok := v.(unresolved); ok {
+			res.AddError(u.err)
+			res.unresolved = append(res.unresolved, u.tuple)
 		} else if err, ok := v.(error); ok {
 			res.AddError(err)
 		} else {
@@ -78,14 +171,62 @@ func Read(r io.Reader) (*Result, error) {
 		}
 	}
 
-	res.Fix()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	res.Fix(ctx, opts)
 	return res, nil
 }
 
+// unresolved is a tuple with unknown mirror, along with its SourceError.
+type unresolved struct {
+	tuple *tuple.Tuple
+	err   error
+}
+
 type Result struct {
-	tuples    tuple.Slice
-	errSource []error
-	errOther  []error
+	tuples     tuple.Slice
+	unresolved tuple.Slice
+	opts       *tuple.Options // options tuples were resolved with
+	errSource  []error
+	errOther   []error
+	skipped    []string
+}
+
+// Tuples returns all resolved tuples, including hidden and linked ones.
+func (r *Result) Tuples() tuple.Slice {
+	return append(tuple.Slice(nil), r.tuples...)
+}
+
+// Links returns tuples that are symlinked in post-extract target.
+func (r *Result) Links() tuple.Links {
+	return r.tuples.Links()
+}
+
+// Unresolved returns tuples of packages with unknown mirrors.
+func (r *Result) Unresolved() tuple.Slice {
+	return append(tuple.Slice(nil), r.unresolved...)
+}
+
+// SourceErrors returns errors for packages with unknown mirrors.
+func (r *Result) SourceErrors() []tuple.SourceError {
+	res := make([]tuple.SourceError, 0, len(r.errSource))
+	for _, err := range r.errSource {
+		res = append(res, err.(tuple.SourceError))
+	}
+	return res
+}
+
+// Skipped returns specs of modules skipped because they don't provide any
+// vendored packages.
+func (r *Result) Skipped() []string {
+	return append([]string(nil), r.skipped...)
+}
+
+// Errors returns all other errors.
+func (r *Result) Errors() []error {
+	return append([]error(nil), r.errOther...)
 }
 
 func (r *Result) AddTuple(t *tuple.Tuple) {
@@ -101,8 +242,8 @@ func (r *Result) AddError(err error) {
 	}
 }
 
-func (r *Result) Fix() {
-	if err := r.tuples.Fix(); err != nil {
+func (r *Result) Fix(ctx context.Context, opts *tuple.Options) {
+	if err := r.tuples.Fix(ctx, opts); err != nil {
 		r.AddError(err)
 	}
 }
@@ -118,6 +259,19 @@ func (errs errSlice) String() string {
 }
 
 func (r *Result) String() string {
+	return r.Makefile(MakefileOptions{})
+}
+
+// MakefileOptions control optional parts of the Makefile output.
+type MakefileOptions struct {
+	// Versions adds comments listing the original Go module version of each tuple.
+	Versions bool
+	// Cleanup removes files that aren't part of vendored packages in post-extract.
+	Cleanup bool
+}
+
+// Makefile returns tuples, comments and post-extract target for the port Makefile.
+func (r *Result) Makefile(o MakefileOptions) string {
 	var lines []string
 
 	if len(r.tuples) > 0 {
@@ -126,6 +280,12 @@ func (r *Result) String() string {
 		lines = append(lines, b.String())
 	}
 
+	if o.Versions {
+		if vc := r.tuples.VersionComments(); vc != "" {
+			lines = append(lines, vc)
+		}
+	}
+
 	if len(r.errSource) > 0 {
 		var b bytes.Buffer
 		b.WriteString("\t\t# Mirrors for the following packages are not currently known, please look them up and handle these tuples manually:\n")
@@ -136,6 +296,19 @@ func (r *Result) String() string {
 		lines = append(lines, b.String())
 	}
 
+	if len(r.skipped) > 0 {
+		var b bytes.Buffer
+		b.WriteString("\t\t# The following modules don't provide any vendored packages and were skipped:\n")
+		sort.Strings(r.skipped)
+		for i, s := range r.skipped {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(fmt.Sprintf("\t\t#\t%s", s))
+		}
+		lines = append(lines, b.String())
+	}
+
 	if len(r.errOther) > 0 {
 		var b bytes.Buffer
 		b.WriteString("\t\t# Errors found during processing:\n")
@@ -143,10 +316,78 @@ func (r *Result) String() string {
 		lines = append(lines, b.String())
 	}
 
-	links := r.tuples.Links()
-	if len(links) > 0 {
-		lines = append(lines, links.String())
+	pe := r.tuples.PostExtract(r.opts)
+	if o.Cleanup {
+		pe = r.tuples.PostExtractWithCleanup(r.opts)
+	}
+	if pe != "" {
+		lines = append(lines, pe)
+	}
+
+	return strings.Join(lines, "\n\n")
+}
+
+// Distfiles returns distfile names of all tuples.
+func (r *Result) Distfiles() []string {
+	return r.tuples.Distfiles()
+}
+
+// PackageReport returns vendored packages of each tuple and an estimate of
+// how much of each module is unused.
+func (r *Result) PackageReport() string {
+	return r.tuples.PackageReport()
+}
+
+// HasErrors returns true if some packages couldn't be processed.
+func (r *Result) HasErrors() bool {
+	return len(r.errSource) > 0 || len(r.errOther) > 0
+}
+
+// GoModules returns GO_MODULES variable contents for all parsed modules,
+// including the ones with unknown mirrors.
+func (r *Result) GoModules() string {
+	var lines []string
+
+	if mods := r.all().GoModules(); mods != "" {
+		lines = append(lines, mods)
+	}
+
+	if len(r.errOther) > 0 {
+		var b bytes.Buffer
+		b.WriteString("\t\t# Errors found during processing:\n")
+		b.WriteString(errSlice(r.errOther).String())
+		lines = append(lines, b.String())
 	}
 
 	return strings.Join(lines, "\n\n")
 }
+
+type resultJSON struct {
+	Tuples       tuple.Slice `json:"tuples"`
+	SourceErrors []string    `json:"source_errors"`
+	Errors       []string    `json:"errors"`
+	Skipped      []string    `json:"skipped,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r *Result) MarshalJSON() ([]byte, error) {
+	v := resultJSON{
+		Tuples:       r.tuples,
+		SourceErrors: errStrings(r.errSource),
+		Errors:       errStrings(r.errOther),
+		Skipped:      r.skipped,
+	}
+	if v.Tuples == nil {
+		v.Tuples = tuple.Slice{}
+	}
+	sort.Strings(v.SourceErrors)
+	return json.Marshal(v)
+}
+
+func errStrings(errs []error) []string {
+	res := []string{}
+	for _, err := range errs {
+		res = append(res, err.Error())
+	}
+	return res
+}
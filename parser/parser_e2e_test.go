@@ -4,12 +4,14 @@
 package parser
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/dmgk/modules2tuple/v2/tuple"
 	"github.com/sergi/go-diff/diffmatchpatch"
 )
 
@@ -31,7 +33,7 @@ func TestParserE2E(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		actual, err := Load(x.modules)
+		actual, err := Load(context.Background(), x.modules, tuple.DefaultOptions())
 		if err != nil {
 			t.Fatal(err)
 		}
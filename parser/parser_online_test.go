@@ -4,15 +4,15 @@
 package parser
 
 import (
+	"context"
 	"strings"
 	"testing"
 
-	"github.com/dmgk/modules2tuple/v2/config"
+	"github.com/dmgk/modules2tuple/v2/apis"
+	"github.com/dmgk/modules2tuple/v2/tuple"
 )
 
-func init() {
-	config.Offline = false
-}
+var onlineOptions = &tuple.Options{Client: apis.NewClient()}
 
 func TestUniqueProjectAndTag(t *testing.T) {
 	given := `
@@ -26,7 +26,7 @@ post-extract:
 	@${MKDIR} ${WRKSRC}/vendor/github.com/ugorji
 	@${RLN} ${WRKSRC_json_iterator_go} ${WRKSRC}/vendor/github.com/ugorji/go`
 
-	tt, err := Read(strings.NewReader(given))
+	tt, err := Read(context.Background(), strings.NewReader(given), onlineOptions)
 	if err != nil {
 		t.Fatal(err)
 	}
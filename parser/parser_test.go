@@ -1,12 +1,19 @@
 package parser
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
-	"github.com/dmgk/modules2tuple/v2/config"
+	"github.com/dmgk/modules2tuple/v2/tuple"
 )
 
+var offlineOptions = &tuple.Options{Offline: true}
+
 func TestReader(t *testing.T) {
 	given := `
 # github.com/karrick/godirwalk v1.10.12
@@ -47,8 +54,7 @@ GL_TUPLE=	gitlab-org:gitaly-proto:v1.32.0:gitlab_org_gitaly_proto/vendor/gitlab.
 		#	::v1.0.0:group_name/vendor/another.vanity_url.org/account/project (from another.vanity_url.org/account/project@v1.0.0)
 		#	::v1.2.3:group_name/vendor/some_unknown.vanity_url.net/account/project (from some_unknown.vanity_url.net/account/project@v1.2.3)`
 
-	config.Offline = true
-	res, err := Read(strings.NewReader(given))
+	res, err := Read(context.Background(), strings.NewReader(given), offlineOptions)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -73,8 +79,252 @@ func TestUniqueGroups(t *testing.T) {
 		minio:minio-go:v6.0.39:minio_minio_go_v6/vendor/github.com/minio/minio-go/v6 \
 		minio:parquet-go:9d767baf1679:minio_parquet_go/vendor/github.com/minio/parquet-go`
 
-	config.Offline = true
-	res, err := Read(strings.NewReader(given))
+	res, err := Read(context.Background(), strings.NewReader(given), offlineOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := res.String()
+	if out != expected {
+		t.Errorf("expected output\n%q\n, got\n%q\n", expected, out)
+	}
+}
+
+func TestDistfileSources(t *testing.T) {
+	given := `
+# git.sr.ht/~sircmpwn/getopt v1.0.0
+# git.sr.ht/~emersion/go-scfg v0.0.0-20201019143924-142a8aa629fc
+# codeberg.org/gruf/go-bytes v1.0.2
+# bitbucket.org/creachadair/shell v0.0.0-20190521151016-4f2e1ab1e1f8
+# github.com/pkg/errors v0.9.1`
+
+	expected := `GH_TUPLE=	pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors
+
+MASTER_SITES+=	\
+		https://bitbucket.org/creachadair/shell/get/4f2e1ab1e1f8.tar.gz?dummy=/:creachadair_shell \
+		https://git.sr.ht/~emersion/go-scfg/archive/142a8aa629fc.tar.gz?dummy=/:emersion_go_scfg \
+		https://codeberg.org/gruf/go-bytes/archive/v1.0.2.tar.gz?dummy=/:gruf_go_bytes \
+		https://git.sr.ht/~sircmpwn/getopt/archive/v1.0.0.tar.gz?dummy=/:sircmpwn_getopt
+DISTFILES+=	\
+		creachadair-shell-4f2e1ab1e1f8_BB0.tar.gz:creachadair_shell \
+		emersion-go-scfg-142a8aa629fc_SH0.tar.gz:emersion_go_scfg \
+		gruf-go-bytes-v1.0.2_GT0.tar.gz:gruf_go_bytes \
+		sircmpwn-getopt-v1.0.0_SH0.tar.gz:sircmpwn_getopt
+
+post-extract:
+	@${MKDIR} ${WRKSRC}/vendor/bitbucket.org/creachadair
+	@${MV} ${WRKDIR}/creachadair-shell-4f2e1ab1e1f8 ${WRKSRC}/vendor/bitbucket.org/creachadair/shell
+	@${MKDIR} ${WRKSRC}/vendor/codeberg.org/gruf
+	@${MV} ${WRKDIR}/go-bytes ${WRKSRC}/vendor/codeberg.org/gruf/go-bytes
+	@${MKDIR} ${WRKSRC}/vendor/git.sr.ht/~emersion
+	@${MV} ${WRKDIR}/go-scfg-142a8aa629fc ${WRKSRC}/vendor/git.sr.ht/~emersion/go-scfg
+	@${MKDIR} ${WRKSRC}/vendor/git.sr.ht/~sircmpwn
+	@${MV} ${WRKDIR}/getopt-v1.0.0 ${WRKSRC}/vendor/git.sr.ht/~sircmpwn/getopt`
+
+	res, err := Read(context.Background(), strings.NewReader(given), offlineOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := res.String()
+	if out != expected {
+		t.Errorf("expected output\n%q\n, got\n%q\n", expected, out)
+	}
+}
+
+func TestProxyFallback(t *testing.T) {
+	given := `
+# github.com/pkg/errors v0.9.1
+# some_unknown.vanity_url.net/Account/project v1.2.3
+# github.com/old/module v1.0.0 => another.vanity_url.org/fork/module v1.1.0-0.20190209023717-9147687966d9+incompatible`
+
+	expected := `GH_TUPLE=	pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors
+
+MASTER_SITES+=	https://proxy.golang.org/another.vanity_url.org/fork/module/@v/v1.1.0-0.20190209023717-9147687966d9+incompatible.zip?dummy=/:another_vanity_url_org_fork_module \
+		https://proxy.golang.org/some_unknown.vanity_url.net/!account/project/@v/v1.2.3.zip?dummy=/:some_unknown_vanity_url_net_account_project
+DISTFILES+=	another.vanity_url.org-fork-module-v1.1.0-0.20190209023717-9147687966d9+incompatible_GP0.zip:another_vanity_url_org_fork_module \
+		some_unknown.vanity_url.net-Account-project-v1.2.3_GP0.zip:some_unknown_vanity_url_net_account_project
+
+post-extract:
+	@${MKDIR} ${WRKSRC}/vendor/github.com/old
+	@${MV} ${WRKDIR}/another.vanity_url.org/fork/module@v1.1.0-0.20190209023717-9147687966d9+incompatible ${WRKSRC}/vendor/github.com/old/module
+	@${MKDIR} ${WRKSRC}/vendor/some_unknown.vanity_url.net/Account
+	@${MV} ${WRKDIR}/some_unknown.vanity_url.net/Account/project@v1.2.3 ${WRKSRC}/vendor/some_unknown.vanity_url.net/Account/project`
+
+	opts := &tuple.Options{Offline: true, GoProxy: tuple.DefaultGoProxy}
+	res, err := Read(context.Background(), strings.NewReader(given), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.HasErrors() {
+		t.Errorf("expected no errors, got %v", res.SourceErrors())
+	}
+	out := res.String()
+	if out != expected {
+		t.Errorf("expected output\n%q\n, got\n%q\n", expected, out)
+	}
+}
+
+func TestModuleVersions(t *testing.T) {
+	given := `
+# github.com/hjson/hjson-go v3.0.1-0.20190209023717-9147687966d9+incompatible
+# github.com/json-iterator/go v1.1.7
+## explicit; go 1.12
+# github.com/pkg/errors v0.9.1
+## explicit`
+
+	expected := `GH_TUPLE=	hjson:hjson-go:9147687966d9:hjson_hjson_go/vendor/github.com/hjson/hjson-go \
+		json-iterator:go:v1.1.7:json_iterator_go/vendor/github.com/json-iterator/go \
+		pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors
+
+		# Go module versions:
+		#	hjson_hjson_go: github.com/hjson/hjson-go v3.0.1-0.20190209023717-9147687966d9+incompatible
+		#	json_iterator_go: github.com/json-iterator/go v1.1.7 (go 1.12)
+		#	pkg_errors: github.com/pkg/errors v0.9.1`
+
+	res, err := Read(context.Background(), strings.NewReader(given), offlineOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := res.Makefile(MakefileOptions{Versions: true})
+	if out != expected {
+		t.Errorf("expected output\n%q\n, got\n%q\n", expected, out)
+	}
+
+	for _, tt := range res.Tuples() {
+		if tt.Package() == "github.com/hjson/hjson-go" && !tt.Incompatible() {
+			t.Errorf("expected %s to be incompatible", tt.Package())
+		}
+		if tt.Package() == "github.com/pkg/errors" && (!tt.Explicit() || tt.GoVersion() != "") {
+			t.Errorf("expected %s to be explicit with no go version, got %v %q", tt.Package(), tt.Explicit(), tt.GoVersion())
+		}
+	}
+}
+
+func TestSkipUnused(t *testing.T) {
+	given := `
+# github.com/json-iterator/go v1.1.7
+## explicit; go 1.12
+github.com/json-iterator/go
+# github.com/modern-go/reflect2 v1.0.1
+## explicit
+# github.com/pkg/errors v0.9.1
+github.com/pkg/errors
+# golang.org/x/sys v0.0.0-20190726091711-fc99dfbffb4e`
+
+	expected := `GH_TUPLE=	json-iterator:go:v1.1.7:json_iterator_go/vendor/github.com/json-iterator/go \
+		pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors
+
+		# The following modules don't provide any vendored packages and were skipped:
+		#	github.com/modern-go/reflect2 v1.0.1
+		#	golang.org/x/sys v0.0.0-20190726091711-fc99dfbffb4e`
+
+	opts := &tuple.Options{Offline: true, SkipUnused: true}
+	res, err := Read(context.Background(), strings.NewReader(given), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := res.String()
+	if out != expected {
+		t.Errorf("expected output\n%q\n, got\n%q\n", expected, out)
+	}
+
+	res, err = Read(context.Background(), strings.NewReader(given), offlineOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(res.Tuples()); n != 4 {
+		t.Errorf("expected 4 tuples without SkipUnused, got %d", n)
+	}
+	if n := len(res.Skipped()); n != 0 {
+		t.Errorf("expected no skipped modules without SkipUnused, got %d", n)
+	}
+}
+
+func TestPackages(t *testing.T) {
+	given := `
+# cloud.google.com/go v0.100.0
+## explicit
+cloud.google.com/go/compute/metadata
+cloud.google.com/go/internal/trace
+# cloud.google.com/go/storage v1.10.0
+## explicit
+cloud.google.com/go/storage
+# github.com/pkg/errors v0.9.1
+## explicit
+github.com/pkg/errors`
+
+	modcache := t.TempDir()
+	for _, name := range []string{
+		"cloud.google.com/go@v0.100.0/doc.go",
+		"cloud.google.com/go@v0.100.0/bigquery/bigquery.go",
+		"cloud.google.com/go@v0.100.0/compute/metadata/metadata.go",
+		"cloud.google.com/go@v0.100.0/internal/trace/trace.go",
+		"cloud.google.com/go@v0.100.0/pubsub/pubsub.go",
+		"cloud.google.com/go@v0.100.0/pubsub/pubsub_test.go",
+		"cloud.google.com/go@v0.100.0/storage/go.mod",
+		"cloud.google.com/go@v0.100.0/storage/storage.go",
+	} {
+		path := filepath.Join(modcache, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectedReport := `googleapis_google_cloud_go: cloud.google.com/go v0.100.0, vendored packages: 2 of 5 (~60% unused)
+	cloud.google.com/go/compute/metadata
+	cloud.google.com/go/internal/trace
+googleapis_google_cloud_go_storage: cloud.google.com/go/storage v1.10.0, vendored packages: 1
+	cloud.google.com/go/storage
+pkg_errors: github.com/pkg/errors v0.9.1, vendored packages: 1
+	github.com/pkg/errors`
+
+	expectedPostExtract := `post-extract:
+	@${FIND} ${WRKSRC}/vendor/cloud.google.com/go -mindepth 2 -type f ! -path '${WRKSRC}/vendor/cloud.google.com/go/compute/metadata/*' ! -path '${WRKSRC}/vendor/cloud.google.com/go/internal/trace/*' ! -path '${WRKSRC}/vendor/cloud.google.com/go/storage/*' -delete
+	@${FIND} ${WRKSRC}/vendor/cloud.google.com/go -mindepth 1 -type d -empty -delete`
+
+	opts := &tuple.Options{Offline: true, ModCache: modcache}
+	res, err := Read(context.Background(), strings.NewReader(given), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := res.PackageReport(); out != expectedReport {
+		t.Errorf("expected report\n%s\n, got\n%s\n", expectedReport, out)
+	}
+	out := res.Makefile(MakefileOptions{Cleanup: true})
+	if !strings.HasSuffix(out, "\n\n"+expectedPostExtract) {
+		t.Errorf("expected output to end with\n%s\n, got\n%s\n", expectedPostExtract, out)
+	}
+	if out := res.String(); strings.Contains(out, "${FIND}") {
+		t.Errorf("expected no cleanup without MakefileOptions.Cleanup, got\n%s\n", out)
+	}
+}
+
+func TestAliases(t *testing.T) {
+	given := `
+# github.com/Sirupsen/logrus v1.4.2
+# github.com/sirupsen/logrus v1.4.2
+# gopkg.in/fsnotify.v1 v1.4.7
+# github.com/fsnotify/fsnotify v1.4.7
+# github.com/docker/docker v1.13.1
+# github.com/pkg/errors v0.9.1
+# github.com/spf13/pflag v1.0.5`
+
+	expected := `GH_TUPLE=	\
+		fsnotify:fsnotify:v1.4.7:fsnotify_fsnotify/vendor/github.com/fsnotify/fsnotify \
+		moby:moby:v1.13.1:moby_moby/vendor/github.com/docker/docker \
+		pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors \
+		sirupsen:logrus:v1.4.2:sirupsen_logrus/vendor/github.com/sirupsen/logrus \
+		spf13:pflag:v1.0.5:spf13_pflag/vendor/github.com/spf13/pflag
+
+post-extract:
+	@${MKDIR} ${WRKSRC}/vendor/github.com/Sirupsen
+	@${RLN} ${WRKSRC_sirupsen_logrus} ${WRKSRC}/vendor/github.com/Sirupsen/logrus
+	@${MKDIR} ${WRKSRC}/vendor/gopkg.in
+	@${RLN} ${WRKSRC_fsnotify_fsnotify} ${WRKSRC}/vendor/gopkg.in/fsnotify.v1`
+
+	res, err := Read(context.Background(), strings.NewReader(given), offlineOptions)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -83,3 +333,106 @@ func TestUniqueGroups(t *testing.T) {
 		t.Errorf("expected output\n%q\n, got\n%q\n", expected, out)
 	}
 }
+
+func TestResultJSON(t *testing.T) {
+	given := `
+# github.com/json-iterator/go v1.1.7
+## explicit; go 1.12
+# gitlab.com/gitlab-org/labkit v0.0.0-20190221122536-0c3fc7cdd57c
+# github.com/hashicorp/vault/api v1.0.5-0.20200215224050-f6547fa8e820 => ./api
+## explicit
+# some_unknown.vanity_url.net/account/project v1.2.3`
+
+	expected := `{"tuples":[` +
+		`{"pkg":"gitlab.com/gitlab-org/labkit","version":"0c3fc7cdd57c","source":"gitlab","account":"gitlab-org","project":"labkit","group":"gitlab_org_labkit","subdir":"vendor/gitlab.com/gitlab-org/labkit","module_version":"v0.0.0-20190221122536-0c3fc7cdd57c","time":"2019-02-21T12:25:36Z"},` +
+		`{"pkg":"github.com/hashicorp/vault/api","version":"f6547fa8e820","source":"github","account":"hashicorp","project":"vault","group":"hashicorp_vault_api","subdir":"github.com/hashicorp/vault/api","module":"api","link_source":"hashicorp_vault_api","link_target":"./api","module_version":"v1.0.5-0.20200215224050-f6547fa8e820","time":"2020-02-15T22:40:50Z","base_version":"v1.0.4","explicit":true},` +
+		`{"pkg":"github.com/json-iterator/go","version":"v1.1.7","source":"github","account":"json-iterator","project":"go","group":"json_iterator_go","subdir":"vendor/github.com/json-iterator/go","module_version":"v1.1.7","explicit":true,"go_version":"1.12"}],` +
+		`"source_errors":["::v1.2.3:group_name/vendor/some_unknown.vanity_url.net/account/project (from some_unknown.vanity_url.net/account/project@v1.2.3)"],` +
+		`"errors":[]}`
+
+	res, err := Read(context.Background(), strings.NewReader(given), offlineOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != expected {
+		t.Errorf("expected output\n%s\n, got\n%s\n", expected, out)
+	}
+}
+
+func TestResultGoModules(t *testing.T) {
+	given := `
+# github.com/hjson/hjson-go v3.0.1-0.20190209023717-9147687966d9+incompatible
+# github.com/karrick/godirwalk v1.10.12
+# github.com/spf13/cobra v0.0.0-20180412120829-615425954c3b => github.com/rsteube/cobra v0.0.1-zsh-completion-custom
+# github.com/hashicorp/vault/api v1.0.5-0.20200215224050-f6547fa8e820 => ./api
+# some_unknown.vanity_url.net/account/project v1.2.3`
+
+	expected := `GO_MODULES=	\
+		github.com/hjson/hjson-go:v3.0.1-0.20190209023717-9147687966d9+incompatible \
+		github.com/karrick/godirwalk:v1.10.12 \
+		github.com/rsteube/cobra:v0.0.1-zsh-completion-custom:github.com/spf13/cobra \
+		some_unknown.vanity_url.net/account/project:v1.2.3`
+
+	res, err := Read(context.Background(), strings.NewReader(given), offlineOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := res.GoModules()
+	if out != expected {
+		t.Errorf("expected output\n%q\n, got\n%q\n", expected, out)
+	}
+}
+
+func TestReadCanceled(t *testing.T) {
+	given := `
+# github.com/pkg/errors v0.8.1
+# golang.org/x/sys v0.0.0-20190726091711-fc99dfbffb4e`
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Read(ctx, strings.NewReader(given), offlineOptions)
+	if err != context.Canceled {
+		t.Errorf("expected error %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestResultAccessors(t *testing.T) {
+	given := `
+# github.com/hashicorp/vault/api v1.0.5-0.20200215224050-f6547fa8e820 => ./api
+# github.com/json-iterator/go v1.1.7
+# some_unknown.vanity_url.net/account/project v1.2.3`
+
+	res, err := Read(context.Background(), strings.NewReader(given), offlineOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tuples []string
+	for _, tt := range res.Tuples() {
+		tuples = append(tuples, fmt.Sprintf("%s %s %s/%s@%s %s", tt.ModulePath(), tt.ModuleVersion(), tt.Account(), tt.Project(), tt.Version(), tt.Group()))
+	}
+	expectedTuples := []string{
+		"github.com/hashicorp/vault/api v1.0.5-0.20200215224050-f6547fa8e820 hashicorp/vault@f6547fa8e820 hashicorp_vault_api",
+		"github.com/json-iterator/go v1.1.7 json-iterator/go@v1.1.7 json_iterator_go",
+	}
+	if strings.Join(tuples, "\n") != strings.Join(expectedTuples, "\n") {
+		t.Errorf("expected tuples\n%s\n, got\n%s\n", strings.Join(expectedTuples, "\n"), strings.Join(tuples, "\n"))
+	}
+
+	links := res.Links()
+	if len(links) != 1 || links[0].LinkTarget() != "./api" || links[0].LinkSource().Group() != "hashicorp_vault_api" {
+		t.Errorf("expected ./api link to hashicorp_vault_api, got %v", links)
+	}
+
+	unresolved := res.Unresolved()
+	if len(res.SourceErrors()) != 1 || len(unresolved) != 1 || unresolved[0].Package() != "some_unknown.vanity_url.net/account/project" {
+		t.Errorf("expected some_unknown.vanity_url.net/account/project source error, got %v", res.SourceErrors())
+	}
+	if len(res.Errors()) != 0 {
+		t.Errorf("expected no errors, got %v", res.Errors())
+	}
+}
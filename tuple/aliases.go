@@ -0,0 +1,77 @@
+package tuple
+
+import (
+	"fmt"
+	"sort"
+)
+
+// alias is the canonical Github account and project of a package that can be
+// required under multiple import paths.
+type alias struct {
+	account string
+	project string
+}
+
+// aliases map import paths to the canonical account and project.
+var aliases = map[string]alias{
+	"github.com/fsnotify/fsnotify":  {"fsnotify", "fsnotify"},
+	"gopkg.in/fsnotify.v1":          {"fsnotify", "fsnotify"},
+	"gopkg.in/fsnotify/fsnotify.v1": {"fsnotify", "fsnotify"},
+	"github.com/sirupsen/logrus":    {"sirupsen", "logrus"},
+	"github.com/Sirupsen/logrus":    {"sirupsen", "logrus"},
+	"github.com/docker/docker":      {"moby", "moby"},
+	"github.com/moby/moby":          {"moby", "moby"},
+}
+
+// lookupAlias returns canonical account and project of pkg, if it's a known
+// alias. User-defined aliases are consulted first.
+func (o *Options) lookupAlias(pkg string) (alias, bool) {
+	if o != nil && o.Mirrors != nil {
+		if a, ok := o.Mirrors.aliases[pkg]; ok {
+			return a, true
+		}
+	}
+	a, ok := aliases[pkg]
+	return a, ok
+}
+
+// isAlias reports whether t is a Github tuple of an aliased package resolved
+// to its canonical account and project.
+func (t *Tuple) isAlias(opts *Options) bool {
+	a, ok := opts.lookupAlias(t.pkg)
+	return ok && t.source == GH && t.account == a.account && t.project == a.project
+}
+
+// fixAliases takes care of packages appearing under multiple import paths.
+// Tuples of aliased packages at the same version are linked to a single one,
+// the tuple of the canonical import path github.com/account/project is
+// preferred.
+func fixAliases(s Slice, opts *Options) {
+	var tuples Slice
+	for _, t := range s {
+		if t.isAlias(opts) && !t.hidden && !t.isLinked() {
+			tuples = append(tuples, t)
+		}
+	}
+
+	key := func(i int) string {
+		t := tuples[i]
+		canonical := fmt.Sprintf("github.com/%s/%s", t.account, t.project)
+		return fmt.Sprintf("%s:%s:%s:%t:%s", t.account, t.project, t.version, t.pkg != canonical, t.pkg)
+	}
+	sort.Slice(tuples, func(i, j int) bool {
+		return key(i) < key(j)
+	})
+
+	var prevTuple *Tuple
+
+	for _, t := range tuples {
+		if prevTuple != nil && t.account == prevTuple.account && t.project == prevTuple.project && t.version == prevTuple.version {
+			opts.Logf("[fixAliases] linking %s@%s => %s@%s\n", prevTuple.pkg, prevTuple.version, t.pkg, t.version)
+			t.makeLinkedAs(prevTuple)
+			t.hidden = true
+			continue
+		}
+		prevTuple = t
+	}
+}
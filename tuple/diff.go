@@ -0,0 +1,226 @@
+package tuple
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ChangeKind is a kind of tuple change between two tuple sets.
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Bumped
+	TagToCommit
+	CommitToTag
+)
+
+var changeKindTitles = map[ChangeKind]string{
+	Added:       "Added",
+	Removed:     "Removed",
+	Bumped:      "Version changed",
+	TagToCommit: "Switched from tag to commit",
+	CommitToTag: "Switched from commit to tag",
+}
+
+// Change describes a single changed tuple, keyed by its Go module path or the
+// directory the tuple is extracted or symlinked to, without "vendor/" prefix.
+type Change struct {
+	Kind ChangeKind
+	Path string
+	Old  *Tuple // nil if tuple was added
+	New  *Tuple // nil if tuple was removed
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("%s %s (%s)", c.Path, c.New.version, c.New)
+	case Removed:
+		return fmt.Sprintf("%s %s (%s)", c.Path, c.Old.version, c.Old)
+	default:
+		res := fmt.Sprintf("%s %s -> %s", c.Path, c.Old.version, c.New.version)
+		if c.Old.account != c.New.account || c.Old.project != c.New.project {
+			res = fmt.Sprintf("%s (%s/%s -> %s/%s)", res, c.Old.account, c.Old.project, c.New.account, c.New.project)
+		}
+		return res
+	}
+}
+
+// Changes is a list of tuple changes.
+type Changes []Change
+
+// Diff compares old and new tuple sets and returns added, removed and changed tuples.
+// Tuples are matched by Go module path if it's known for both of them. Tuples
+// recovered from the port Makefile don't know it and are matched by the
+// directory they are extracted or symlinked to instead. Tuples symlinked to
+// another tuple are compared as the tuple they are fetched as. It's an error
+// for tuples of the same set to share a module path or a directory.
+func Diff(old, new Slice) (Changes, error) {
+	oldByMod, err := old.byModPath()
+	if err != nil {
+		return nil, err
+	}
+	newByMod, err := new.byModPath()
+	if err != nil {
+		return nil, err
+	}
+
+	var res Changes
+
+	// tuples matched by module path
+	matched := map[*Tuple]bool{}
+	for path, t := range oldByMod {
+		if nt, ok := newByMod[path]; ok {
+			matched[t], matched[nt] = true, true
+			if c, ok := change(path, t, nt); ok {
+				res = append(res, c)
+			}
+		}
+	}
+
+	oldByDir, err := old.byDir(matched)
+	if err != nil {
+		return nil, err
+	}
+	newByDir, err := new.byDir(matched)
+	if err != nil {
+		return nil, err
+	}
+
+	for dir, t := range oldByDir {
+		p := strings.TrimPrefix(dir, "vendor/")
+		nt, ok := newByDir[dir]
+		if !ok {
+			res = append(res, Change{Removed, p, t, nil})
+			continue
+		}
+		if c, ok := change(p, t, nt); ok {
+			res = append(res, c)
+		}
+	}
+	for dir, t := range newByDir {
+		if _, ok := oldByDir[dir]; !ok {
+			res = append(res, Change{Added, strings.TrimPrefix(dir, "vendor/"), nil, t})
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Kind != res[j].Kind {
+			return res[i].Kind < res[j].Kind
+		}
+		return res[i].Path < res[j].Path
+	})
+
+	return res, nil
+}
+
+// change returns the change between old and new tuples at path, if any.
+func change(path string, old, new *Tuple) (Change, bool) {
+	old, new = old.fetched(), new.fetched()
+	if sameVersion(old.version, new.version) && old.account == new.account && old.project == new.project {
+		return Change{}, false
+	}
+	kind := Bumped
+	switch oc, nc := isCommitID(old.version), isCommitID(new.version); {
+	case !oc && nc:
+		kind = TagToCommit
+	case oc && !nc:
+		kind = CommitToTag
+	}
+	return Change{kind, path, old, new}, true
+}
+
+// String returns human-readable changes report.
+func (cc Changes) String() string {
+	if len(cc) == 0 {
+		return "No changes"
+	}
+
+	var sections []string
+	for i := 0; i < len(cc); {
+		kind := cc[i].Kind
+
+		var b bytes.Buffer
+		b.WriteString(changeKindTitles[kind] + ":")
+		for ; i < len(cc) && cc[i].Kind == kind; i++ {
+			b.WriteString("\n\t" + cc[i].String())
+		}
+		sections = append(sections, b.String())
+	}
+
+	return strings.Join(sections, "\n\n")
+}
+
+// byModPath returns tuples keyed by Go module path, only tuples resolved from
+// Go modules or fetched from the module proxy know it.
+func (s Slice) byModPath() (map[string]*Tuple, error) {
+	res := map[string]*Tuple{}
+	for _, t := range s {
+		if t.modVersion == "" || t.modPath == "" {
+			continue
+		}
+		if prev, ok := res[t.modPath]; ok {
+			return nil, fmt.Errorf("%s and %s are both module %s", prev, t, t.modPath)
+		}
+		res[t.modPath] = t
+	}
+	return res, nil
+}
+
+// byDir returns tuples keyed by the directory relative to WRKSRC they are
+// extracted or symlinked to. Hidden tuples extracted as a part of another
+// tuple and tuples in skip are skipped.
+func (s Slice) byDir(skip map[*Tuple]bool) (map[string]*Tuple, error) {
+	res := map[string]*Tuple{}
+	for _, t := range s {
+		if skip[t] || t.hidden && !t.isLinked() {
+			continue
+		}
+		dir := t.subdirPath()
+		if t.isLinked() {
+			dir = t.link_tgt
+		}
+		if dir == "" {
+			continue
+		}
+		dir = filepath.Clean(dir)
+		if prev, ok := res[dir]; ok {
+			return nil, fmt.Errorf("%s and %s both end up in ${WRKSRC}/%s", prev, t, dir)
+		}
+		res[dir] = t
+	}
+	return res, nil
+}
+
+// fetched returns the tuple t is fetched as, link source for tuples
+// symlinked to another tuple.
+func (t *Tuple) fetched() *Tuple {
+	if t.link_src != nil {
+		return t.link_src
+	}
+	return t
+}
+
+var commitIDRe = regexp.MustCompile(`\A[0-9a-f]{7,40}\z`)
+
+func isCommitID(version string) bool {
+	return commitIDRe.MatchString(version)
+}
+
+// sameVersion returns true if versions are equal or are the same commit ID,
+// possibly of different length.
+func sameVersion(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if isCommitID(a) && isCommitID(b) {
+		return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+	}
+	return false
+}
@@ -1,46 +1,54 @@
 package tuple
 
 import (
+	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
 )
 
-func discoverMirrors(pkg string) (string, error) {
+// discoverMirrors looks up pkg repository using go-import meta tags. It returns
+// repository path with the rest of pkg past the import prefix appended, so that
+// module subdir is preserved, or empty string if no git repository is found.
+func discoverMirrors(ctx context.Context, pkg string, opts *Options) (string, error) {
 	u, err := pkgURL(pkg)
 	if err != nil {
 		return "", err
 	}
-	resp, err := http.Get(u)
+
+	body, err := opts.client().Get(ctx, u)
 	if err != nil {
-		return "", fmt.Errorf("tuple.discoverMirrors %s: %v", u, err)
+		return "", fmt.Errorf("tuple.discoverMirrors %s: %w", u, err)
 	}
-	defer resp.Body.Close()
-
-	switch resp.StatusCode {
-	case http.StatusOK:
-		return parseMetaGoImports(resp.Body)
-	default:
-		return "", fmt.Errorf("tuple.discoverMirrors %s: status %d", u, resp.StatusCode)
+	prefix, repo, err := parseMetaGoImports(bytes.NewReader(body), pkg)
+	if err != nil || repo == "" {
+		return "", err
 	}
+	return repo + strings.TrimPrefix(pkg, prefix), nil
 }
 
+// parseMetaGoImports returns import prefix and repository root of the git
+// go-import meta tag with the longest prefix matching pkg. Home URL of
+// go-source meta tag is used as repository root if there's no such go-import.
+//
 // HTML meta imports discovery code adopted from
 // https://github.com/golang/go/blob/master/src/cmd/go/internal/get/discovery.go
-func parseMetaGoImports(r io.Reader) (string, error) {
+func parseMetaGoImports(r io.Reader, pkg string) (string, string, error) {
 	d := xml.NewDecoder(r)
 	d.CharsetReader = charsetReader
 	d.Strict = false
 
+	var prefix, repo, sourcePrefix, sourceHome string
+
 	for {
 		t, err := d.RawToken()
 		if err != nil {
 			if err != io.EOF {
-				return "", err
+				return "", "", err
 			}
 			break
 		}
@@ -54,17 +62,35 @@ func parseMetaGoImports(r io.Reader) (string, error) {
 		if !ok || !strings.EqualFold(e.Name.Local, "meta") {
 			continue
 		}
-		if attrValue(e.Attr, "name") != "go-import" {
-			continue
-		}
-		if f := strings.Fields(attrValue(e.Attr, "content")); len(f) == 3 {
-			if f[1] == "git" {
-				repo := schemeRe.ReplaceAllString(f[2], "")
-				return suffixRe.ReplaceAllString(repo, ""), nil
+		f := strings.Fields(attrValue(e.Attr, "content"))
+		switch attrValue(e.Attr, "name") {
+		case "go-import":
+			// import-prefix vcs repo-root
+			if len(f) == 3 && f[1] == "git" && hasPathPrefix(pkg, f[0]) && len(f[0]) > len(prefix) {
+				prefix, repo = f[0], f[2]
+			}
+		case "go-source":
+			// import-prefix home directory file
+			if len(f) >= 2 && hasPathPrefix(pkg, f[0]) && len(f[0]) > len(sourcePrefix) && f[1] != "_" {
+				sourcePrefix, sourceHome = f[0], f[1]
 			}
 		}
 	}
-	return "", nil
+
+	if repo == "" {
+		prefix, repo = sourcePrefix, sourceHome
+	}
+	if repo == "" {
+		return "", "", nil
+	}
+	repo = schemeRe.ReplaceAllString(repo, "")
+	repo = suffixRe.ReplaceAllString(strings.TrimSuffix(repo, "/"), "")
+	return prefix, repo, nil
+}
+
+// hasPathPrefix reports whether import path pkg is prefix or is in a subdirectory of it.
+func hasPathPrefix(pkg, prefix string) bool {
+	return pkg == prefix || strings.HasPrefix(pkg, prefix+"/")
 }
 
 var (
@@ -0,0 +1,92 @@
+package tuple
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dmgk/modules2tuple/v2/apis"
+)
+
+func TestParseMetaGoImports(t *testing.T) {
+	examples := []struct {
+		pkg, html, prefix, repo string
+	}{
+		// import prefix is shorter than package path
+		{
+			"go.example.org/lib/sub/pkg",
+			`<html><head><meta name="go-import" content="go.example.org/lib git https://github.com/account/lib.git"></head></html>`,
+			"go.example.org/lib", "github.com/account/lib",
+		},
+		// the longest matching prefix wins, non-git and non-matching tags are skipped
+		{
+			"go.example.org/lib/v2/pkg",
+			`<meta name="go-import" content="go.example.org/lib mod https://proxy.example.org">
+			<meta name="go-import" content="go.example.org/other git https://github.com/account/other">
+			<meta name="go-import" content="go.example.org/lib git https://github.com/account/lib">
+			<meta name="go-import" content="go.example.org/lib/v2 git https://gitlab.com/account/lib2/">`,
+			"go.example.org/lib/v2", "gitlab.com/account/lib2",
+		},
+		// go-source is a fallback
+		{
+			"go.example.org/lib/pkg",
+			`<meta name="go-import" content="go.example.org/lib mod https://proxy.example.org">
+			<meta name="go-source" content="go.example.org/lib https://github.com/account/lib https://github.com/account/lib/tree/master{/dir} https://github.com/account/lib/blob/master{/dir}/{file}#L{line}">`,
+			"go.example.org/lib", "github.com/account/lib",
+		},
+		// nothing found
+		{
+			"go.example.org/lib",
+			`<meta name="go-import" content="go.example.org/library git https://github.com/account/library"><body><meta name="go-import" content="go.example.org/lib git https://github.com/account/lib">`,
+			"", "",
+		},
+	}
+
+	for i, x := range examples {
+		prefix, repo, err := parseMetaGoImports(strings.NewReader(x.html), x.pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prefix != x.prefix {
+			t.Errorf("(%d) expected prefix to be %q, got %q", i, x.prefix, prefix)
+		}
+		if repo != x.repo {
+			t.Errorf("(%d) expected repo to be %q, got %q", i, x.repo, repo)
+		}
+	}
+}
+
+func TestDiscoverMirrors(t *testing.T) {
+	// vanity hosts, all served by the test server
+	imports := map[string]string{
+		"go.example.org": "go.example.org/lib git https://go.example.net/lib",
+		"go.example.net": "go.example.net/lib git https://github.com/account/lib",
+	}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<html><head><meta name="go-import" content="%s"></head></html>`, imports[r.Host])
+	}))
+	defer srv.Close()
+
+	transport := srv.Client().Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+	opts := &Options{Client: &apis.Client{HTTPClient: &http.Client{Transport: transport}}}
+
+	tuple, err := Resolve(context.Background(), "go.example.org/lib/sub/pkg", "v1.0.0", "go.example.org/lib/sub/pkg", "", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := tuple.String(); s != "account:lib:v1.0.0:account_lib_pkg/vendor/go.example.org/lib/sub/pkg" {
+		t.Errorf("expected String() to return %q, got %q", "account:lib:v1.0.0:account_lib_pkg/vendor/go.example.org/lib/sub/pkg", s)
+	}
+	if tuple.module != "sub/pkg" {
+		t.Errorf("expected module to be %q, got %q", "sub/pkg", tuple.module)
+	}
+}
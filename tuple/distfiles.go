@@ -0,0 +1,113 @@
+package tuple
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// distfileVars returns MASTER_SITES and DISTFILES additions for tuples
+// from distfileSource sources. Archives are fetched using "?dummy=/" trick
+// to give them unique distfile names.
+func (s Slice) distfileVars() string {
+	var sites, distfiles []string
+
+	for _, t := range s {
+		if t.hidden {
+			continue
+		}
+		ds := t.source.(distfileSource)
+		sites = append(sites, fmt.Sprintf("%s?dummy=/:%s", ds.archiveURL(t), t.group))
+		distfiles = append(distfiles, fmt.Sprintf("%s:%s", t.distfile(), t.group))
+	}
+
+	return formatVar("MASTER_SITES+=", sites) + "\n" + formatVar("DISTFILES+=", distfiles)
+}
+
+// wrksrc returns the directory tuple is extracted to.
+func (t *Tuple) wrksrc() string {
+	ds, ok := t.source.(distfileSource)
+	if !ok {
+		return fmt.Sprintf("${WRKSRC_%s}", t.group)
+	}
+	if t.subdirPath() != "" {
+		return filepath.Join("${WRKSRC}", t.subdirPath())
+	}
+	return filepath.Join("${WRKDIR}", ds.archiveDir(t))
+}
+
+// moves returns post-extract recipe lines moving tuples from distfileSource
+// sources extracted into WRKDIR to their subdirs. Directories already created
+// are tracked in dirs.
+func (s Slice) moves(dirs map[string]struct{}) []string {
+	var tuples Slice
+	for _, t := range s {
+		if _, ok := t.source.(distfileSource); ok && !t.hidden && t.subdirPath() != "" {
+			tuples = append(tuples, t)
+		}
+	}
+	sort.Slice(tuples, func(i, j int) bool {
+		return tuples[i].subdirPath() < tuples[j].subdirPath()
+	})
+
+	var lines []string
+	for _, t := range tuples {
+		var b bytes.Buffer
+
+		dir := filepath.Dir(t.subdirPath())
+		if _, ok := dirs[dir]; !ok {
+			b.WriteString(fmt.Sprintf("\t@${MKDIR} %s\n", filepath.Join("${WRKSRC}", dir)))
+			dirs[dir] = struct{}{}
+		}
+		ds := t.source.(distfileSource)
+		b.WriteString(fmt.Sprintf("\t@${MV} %s %s", filepath.Join("${WRKDIR}", ds.archiveDir(t)), t.wrksrc()))
+
+		lines = append(lines, b.String())
+	}
+
+	return lines
+}
+
+// PostExtract returns "post-extract" target contents, moving tuples fetched as
+// regular distfiles in place and creating symlinks. It returns empty string if
+// there's nothing to do.
+func (s Slice) PostExtract(opts *Options) string {
+	dirs := map[string]struct{}{}
+	recipe := append(s.moves(dirs), s.Links().recipe(dirs, opts)...)
+	if len(recipe) == 0 {
+		return ""
+	}
+	return postExtract(recipe)
+}
+
+// PostExtractWithCleanup is PostExtract that also removes files of vendored
+// modules that aren't part of vendored packages, to cut WRKSRC size.
+func (s Slice) PostExtractWithCleanup(opts *Options) string {
+	dirs := map[string]struct{}{}
+	recipe := append(s.moves(dirs), s.Links().recipe(dirs, opts)...)
+	recipe = append(recipe, s.cleanup()...)
+	if len(recipe) == 0 {
+		return ""
+	}
+	return postExtract(recipe)
+}
+
+// formatVar returns variable assignment with values listed one per line.
+func formatVar(assign string, values []string) string {
+	buf := bytes.NewBufferString(assign + "\t")
+	large := len(values) > largeLimit
+	if large {
+		buf.WriteString("\\\n")
+	}
+	for i, v := range values {
+		if i > 0 || large {
+			buf.WriteString("\t\t")
+		}
+		buf.WriteString(v)
+		if i < len(values)-1 {
+			buf.WriteString(" \\\n")
+		}
+	}
+	return buf.String()
+}
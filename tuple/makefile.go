@@ -0,0 +1,186 @@
+package tuple
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dmgk/modules2tuple/v2/apis"
+	"golang.org/x/mod/module"
+)
+
+var (
+	// ${MV} ${WRKDIR}/archive_dir ${WRKSRC}/subdir
+	moveRe = regexp.MustCompile(`\A\t@\$\{MV\} \$\{WRKDIR\}/(\S+) \$\{WRKSRC\}/(\S+)\z`)
+	// ${RLN} source ${WRKSRC}/link_target
+	linkRe = regexp.MustCompile(`\A\t@\$\{RLN\} (\S+) \$\{WRKSRC\}/(\S+)\z`)
+)
+
+// ParseMakefile recovers tuples from the port Makefile: G{H,L}_TUPLE entries
+// in vars, MASTER_SITES entries of tuples fetched as regular distfiles in sites
+// and post-extract recipe lines moving and symlinking them in place. Tuples
+// symlinked under another import path are recovered as hidden tuples linked
+// to the tuple they are fetched as. Entries that can't be parsed are returned
+// as errors.
+func ParseMakefile(vars map[string][]string, sites, recipe []string) (Slice, []error) {
+	var (
+		res  Slice
+		errs []error
+	)
+
+	// directories tuples are extracted to, symlink sources refer to them
+	wrksrc := map[string]*Tuple{}
+	// tuples extracted under vendor/, the rest are symlinked in place
+	vendored := map[*Tuple]bool{}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, entry := range vars[name] {
+			t, err := ParseTuple(name, entry)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			wrksrc[t.wrksrc()] = t
+			vendored[t] = t.subdir != "" && strings.HasSuffix(entry, ":"+t.group+"/vendor/"+t.subdir)
+			res = append(res, t)
+		}
+	}
+
+	archives := map[string]*Tuple{}
+	for _, site := range sites {
+		t, err := parseDistfileSite(site)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		archives[t.source.(distfileSource).archiveDir(t)] = t
+		res = append(res, t)
+	}
+	for _, line := range recipe {
+		sm := moveRe.FindStringSubmatch(line)
+		if sm == nil {
+			continue
+		}
+		t, ok := archives[sm[1]]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unexpected post-extract line: %q", line))
+			continue
+		}
+		delete(archives, sm[1])
+		t.subdir = strings.TrimPrefix(sm[2], "vendor/")
+		t.modPath = t.subdir
+		if t.pkg == "" {
+			t.pkg = t.subdir
+		}
+		vendored[t] = strings.HasPrefix(sm[2], "vendor/")
+		wrksrc["${WRKSRC}/"+sm[2]] = t
+	}
+	// archives extracted into WRKDIR and symlinked in place
+	for dir, t := range archives {
+		wrksrc["${WRKDIR}/"+dir] = t
+	}
+
+	for _, line := range recipe {
+		sm := linkRe.FindStringSubmatch(line)
+		if sm == nil {
+			continue
+		}
+		src, tgt := sm[1], sm[2]
+
+		// the longest directory symlink source is in
+		var (
+			t   *Tuple
+			dir string
+		)
+		for d, dt := range wrksrc {
+			if (src == d || strings.HasPrefix(src, d+"/")) && len(d) > len(dir) {
+				t, dir = dt, d
+			}
+		}
+		if t == nil {
+			errs = append(errs, fmt.Errorf("unexpected post-extract line: %q", line))
+			continue
+		}
+		mod := strings.TrimPrefix(strings.TrimPrefix(src, dir), "/")
+
+		if !vendored[t] && !t.isLinked() {
+			// tuple itself is symlinked in place
+			t.link_tgt = tgt
+			t.module = mod
+			continue
+		}
+		res = append(res, &Tuple{
+			pkg:      strings.TrimPrefix(tgt, "vendor/"),
+			version:  t.version,
+			group:    t.group,
+			module:   mod,
+			link_src: t,
+			link_tgt: tgt,
+			source:   t.source,
+			account:  t.account,
+			project:  t.project,
+			hidden:   true,
+			modPath:  strings.TrimPrefix(tgt, "vendor/"),
+		})
+	}
+
+	return res, errs
+}
+
+var (
+	// https://bitbucket.org/account/project/get/version.tar.gz
+	bitbucketSiteRe = regexp.MustCompile(`\Ahttps://bitbucket\.org/([^/]+)/([^/]+)/get/(.+)\.tar\.gz\z`)
+	// site/~account/project/archive/version.tar.gz
+	sourcehutSiteRe = regexp.MustCompile(`\A(.+?)/~([^/]+)/([^/]+)/archive/(.+)\.tar\.gz\z`)
+	// site/account/project/archive/version.tar.gz
+	giteaSiteRe = regexp.MustCompile(`\A(.+?)/([^/]+)/([^/]+)/archive/(.+)\.tar\.gz\z`)
+	// site/escaped_module_path/@v/escaped_version.zip
+	proxySiteRe = regexp.MustCompile(`\A(https?://[^/]+)/(.+)/@v/([^/]+)\.zip\z`)
+)
+
+// parseDistfileSite parses MASTER_SITES entry "archive_url?dummy=/:group" of
+// a tuple fetched as a regular distfile.
+func parseDistfileSite(entry string) (*Tuple, error) {
+	n := strings.LastIndex(entry, "?dummy=/:")
+	if n < 0 {
+		return nil, fmt.Errorf("unexpected MASTER_SITES entry: %q", entry)
+	}
+	url := entry[:n]
+	t := &Tuple{group: entry[n+len("?dummy=/:"):]}
+
+	site := func(s, def string) string {
+		if strings.TrimSuffix(s, "/") == def {
+			return ""
+		}
+		return s
+	}
+
+	if sm := bitbucketSiteRe.FindStringSubmatch(url); sm != nil {
+		t.source, t.account, t.project, t.version = BB, sm[1], sm[2], sm[3]
+	} else if sm := sourcehutSiteRe.FindStringSubmatch(url); sm != nil {
+		t.source, t.account, t.project, t.version = SourcehutSource(site(sm[1], apis.DefaultSourcehutURL)), sm[2], sm[3], sm[4]
+	} else if sm := giteaSiteRe.FindStringSubmatch(url); sm != nil {
+		t.source, t.account, t.project, t.version = GiteaSource(site(sm[1], apis.DefaultGiteaURL)), sm[2], sm[3], sm[4]
+	} else if sm := proxySiteRe.FindStringSubmatch(url); sm != nil {
+		path, err := module.UnescapePath(sm[2])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected MASTER_SITES entry: %q: %v", entry, err)
+		}
+		version, err := module.UnescapeVersion(sm[3])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected MASTER_SITES entry: %q: %v", entry, err)
+		}
+		t.source, t.pkg, t.version, t.modVersion = ProxySource(site(sm[1], DefaultGoProxy)), path, version, version
+	} else {
+		return nil, fmt.Errorf("unexpected MASTER_SITES entry: %q", entry)
+	}
+
+	return t, nil
+}
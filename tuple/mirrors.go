@@ -0,0 +1,173 @@
+package tuple
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Mirrors are user-defined mirrors and aliases, consulted before the built-in ones.
+type Mirrors struct {
+	resolvers []prefixResolver
+	aliases   map[string]alias
+}
+
+// LoadMirrorsFile loads user-defined mirrors from the file at path.
+func LoadMirrorsFile(path string) (*Mirrors, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := LoadMirrors(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return m, nil
+}
+
+// LoadMirrors loads user-defined mirrors from TOML contents provided as io.Reader.
+// Each mirror is defined in its own [[mirror]] table:
+//
+//	# fixed mapping, matched by package prefix
+//	[[mirror]]
+//	prefix = "code.cloudfoundry.org/gofileutils"
+//	account = "cloudfoundry"
+//	project = "gofileutils"
+//
+//	# regexp mapping, account, project and module can refer to the pattern captures
+//	[[mirror]]
+//	prefix = "code.cloudfoundry.org"
+//	pattern = '\Acode\.cloudfoundry\.org/([0-9A-Za-z][-0-9A-Za-z]+)\z'
+//	account = "cloudfoundry"
+//	project = "$1"
+//
+// Optional "source" key is either "github" (default), "gitlab", "sourcehut", "gitea" or "bitbucket",
+// "site" sets non-default Gitlab, SourceHut or Gitea site URL.
+//
+// Packages that can be required under multiple import paths are mapped to
+// the canonical Github account and project in [[alias]] tables, tuples of
+// aliases at the same version are fetched once:
+//
+//	[[alias]]
+//	path = "github.com/Sirupsen/logrus"
+//	account = "sirupsen"
+//	project = "logrus"
+func LoadMirrors(r io.Reader) (*Mirrors, error) {
+	var mf mirrorsFile
+	md, err := toml.NewDecoder(r).Decode(&mf)
+	if err != nil {
+		return nil, err
+	}
+	if keys := md.Undecoded(); len(keys) > 0 {
+		return nil, fmt.Errorf("unknown key %q", keys[0].String())
+	}
+
+	res := &Mirrors{aliases: map[string]alias{}}
+	for i, tbl := range mf.Mirror {
+		pr, err := tbl.resolver()
+		if err != nil {
+			return nil, fmt.Errorf("mirror %d: %v", i+1, err)
+		}
+		res.resolvers = append(res.resolvers, pr)
+	}
+	for i, tbl := range mf.Alias {
+		if tbl.Path == "" {
+			return nil, fmt.Errorf("alias %d: missing path", i+1)
+		}
+		if tbl.Account == "" || tbl.Project == "" {
+			return nil, fmt.Errorf("alias %d: missing account or project", i+1)
+		}
+		res.aliases[tbl.Path] = alias{tbl.Account, tbl.Project}
+	}
+	return res, nil
+}
+
+// mirrorsFile is the decoded contents of the mirrors file.
+type mirrorsFile struct {
+	Mirror []mirrorTable `toml:"mirror"`
+	Alias  []aliasTable  `toml:"alias"`
+}
+
+// mirrorTable is a single [[mirror]] table.
+type mirrorTable struct {
+	Prefix  string `toml:"prefix"`
+	Pattern string `toml:"pattern"`
+	Source  string `toml:"source"`
+	Site    string `toml:"site"`
+	Account string `toml:"account"`
+	Project string `toml:"project"`
+	Module  string `toml:"module"`
+}
+
+// aliasTable is a single [[alias]] table.
+type aliasTable struct {
+	Path    string `toml:"path"`
+	Account string `toml:"account"`
+	Project string `toml:"project"`
+}
+
+func (tbl mirrorTable) resolver() (prefixResolver, error) {
+	if tbl.Prefix == "" {
+		return prefixResolver{}, fmt.Errorf("missing prefix")
+	}
+	if tbl.Account == "" || tbl.Project == "" {
+		return prefixResolver{}, fmt.Errorf("missing account or project")
+	}
+
+	var source Source
+	switch tbl.Source {
+	case "", "github":
+		if tbl.Site != "" {
+			return prefixResolver{}, fmt.Errorf("site is not supported for Github mirrors")
+		}
+		source = GH
+	case "gitlab":
+		source = GitlabSource(tbl.Site)
+	case "sourcehut":
+		source = SourcehutSource(tbl.Site)
+	case "gitea":
+		source = GiteaSource(tbl.Site)
+	case "bitbucket":
+		if tbl.Site != "" {
+			return prefixResolver{}, fmt.Errorf("site is not supported for Bitbucket mirrors")
+		}
+		source = BB
+	default:
+		return prefixResolver{}, fmt.Errorf("unknown source %q", tbl.Source)
+	}
+
+	m := &mirror{source, tbl.Account, tbl.Project, tbl.Module}
+
+	if tbl.Pattern == "" {
+		return prefixResolver{tbl.Prefix, m}, nil
+	}
+
+	re, err := regexp.Compile(tbl.Pattern)
+	if err != nil {
+		return prefixResolver{}, err
+	}
+	return prefixResolver{tbl.Prefix, &patternMirror{re, m}}, nil
+}
+
+// patternMirror resolves packages matching re, expanding re captures in the mirror
+// account, project and module.
+type patternMirror struct {
+	re *regexp.Regexp
+	m  *mirror
+}
+
+func (pm *patternMirror) resolve(pkg string) (*mirror, error) {
+	sm := pm.re.FindStringSubmatchIndex(pkg)
+	if sm == nil {
+		return nil, nil
+	}
+	expand := func(tmpl string) string {
+		return string(pm.re.ExpandString(nil, tmpl, pkg, sm))
+	}
+	return &mirror{pm.m.source, expand(pm.m.account), expand(pm.m.project), expand(pm.m.module)}, nil
+}
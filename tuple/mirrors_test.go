@@ -0,0 +1,137 @@
+package tuple
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLoadMirrors(t *testing.T) {
+	given := `
+# fixed mapping
+[[mirror]]
+prefix = "code.cloudfoundry.org/gofileutils"
+account = "cloudfoundry"
+project = "gofileutils"
+
+# regexp mapping
+[[mirror]]
+prefix = "code.cloudfoundry.org"
+pattern = '\Acode\.cloudfoundry\.org/([0-9A-Za-z][-0-9A-Za-z]+)(?:/(.+))?\z'
+account = "cloudfoundry"
+project = "${1}-release" # trailing comment
+module = "$2"
+
+[[mirror]]
+prefix = "go.example.org/lib"
+source = "gitlab"
+site = "https://git.example.org"
+account = "libs"
+project = "lib"
+
+[[mirror]]
+prefix = "go.example.org/hut"
+source = "sourcehut"
+account = "user"
+project = "hut"
+
+[[mirror]]
+prefix = "go.example.org/tea"
+source = "gitea"
+site = "https://gitea.example.org/"
+account = "user"
+project = "tea"
+
+[[mirror]]
+prefix = "go.example.org/bucket"
+source = "bitbucket"
+account = "user"
+project = "bucket"
+
+# overrides built-in mirror
+[[mirror]]
+prefix = "go.uber.org/zap"
+account = "uber-go-fork"
+project = "zap"
+
+# overrides built-in alias
+[[mirror]]
+prefix = "github.com/sirupsen/logrus"
+account = "logrus-fork"
+project = "logrus"
+
+[[alias]]
+path = "github.com/docker/engine"
+account = "moby"
+project = "moby"
+`
+
+	mirrors, err := LoadMirrors(strings.NewReader(given))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &Options{Offline: true, Mirrors: mirrors}
+
+	examples := []resolverExample{
+		{"code.cloudfoundry.org/gofileutils", GH, "cloudfoundry", "gofileutils", ""},
+		{"code.cloudfoundry.org/bytefmt", GH, "cloudfoundry", "bytefmt-release", ""},
+		{"code.cloudfoundry.org/lager/v3", GH, "cloudfoundry", "lager-release", "v3"},
+		{"go.example.org/lib", GitlabSource("https://git.example.org"), "libs", "lib", ""},
+		{"go.example.org/hut", SH, "user", "hut", ""},
+		{"go.example.org/tea", GiteaSource("https://gitea.example.org/"), "user", "tea", ""},
+		{"go.example.org/bucket", BB, "user", "bucket", ""},
+		{"go.uber.org/zap", GH, "uber-go-fork", "zap", ""},
+		{"go.uber.org/atomic", GH, "uber-go", "atomic", ""},
+		{"github.com/docker/engine", GH, "moby", "moby", ""},
+		{"github.com/Sirupsen/logrus", GH, "sirupsen", "logrus", ""},
+		{"github.com/sirupsen/logrus", GH, "logrus-fork", "logrus", ""},
+	}
+
+	for i, x := range examples {
+		m, err := Resolve(context.Background(), x.pkg, "", "", "", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprintf("%T %v", m.source, m.source) != fmt.Sprintf("%T %v", x.source, x.source) {
+			t.Errorf("(%d) expected source to be %q, got %q", i, fmt.Sprintf("%T %v", x.source, x.source), fmt.Sprintf("%T %v", m.source, m.source))
+		}
+		if m.account != x.account {
+			t.Errorf("(%d) expected account to be %q, got %q", i, x.account, m.account)
+		}
+		if m.project != x.project {
+			t.Errorf("(%d) expected project to be %q, got %q", i, x.project, m.project)
+		}
+		if m.module != x.module {
+			t.Errorf("(%d) expected module to be %q, got %q", i, x.module, m.module)
+		}
+	}
+}
+
+func TestLoadMirrorsFail(t *testing.T) {
+	examples := [][]string{
+		// mirrors file, expected error
+		{"prefix = \"a\"", "unknown key \"prefix\""},
+		{"[mirror]", "toml: line 1"},
+		{"[[mirror]]\nprefix = a", "toml: line 2"},
+		{"[[mirror]]\nprefix = 1", "toml: line 2"},
+		{"[[mirror]]\nunknown = \"a\"", "unknown key \"mirror.unknown\""},
+		{"[[mirror]]\nprefix = \"a\"\naccount = \"b\"", "mirror 1: missing account or project"},
+		{"[[mirror]]\nprefix = \"a\"\naccount = \"b\"\nproject = \"c\"\nsource = \"svn\"", "mirror 1: unknown source \"svn\""},
+		{"[[mirror]]\nprefix = \"a\"\naccount = \"b\"\nproject = \"c\"\npattern = '('", "mirror 1: error parsing regexp"},
+		{"[[alias]]\naccount = \"b\"\nproject = \"c\"", "alias 1: missing path"},
+		{"[[alias]]\npath = \"a\"\nproject = \"c\"", "alias 1: missing account or project"},
+		{"[[alias]]\nprefix = \"a\"", "unknown key \"alias.prefix\""},
+	}
+
+	for i, x := range examples {
+		_, err := LoadMirrors(strings.NewReader(x[0]))
+		if err == nil {
+			t.Errorf("(%d) expected to fail: %q", i, x[0])
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), x[1]) {
+			t.Errorf("(%d) expected error to start with %q, got %q", i, x[1], err.Error())
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package tuple
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// modOrigin is the VCS origin of a module version, as recorded by the go
+// command in the module cache.
+type modOrigin struct {
+	VCS    string `json:"VCS"`
+	URL    string `json:"URL"`
+	Subdir string `json:"Subdir"`
+	Ref    string `json:"Ref"`
+	Hash   string `json:"Hash"`
+}
+
+// repoPath returns origin repository URL without scheme and ".git" suffix,
+// suitable for mirror lookup.
+func (o *modOrigin) repoPath() string {
+	p := o.URL
+	if n := strings.Index(p, "://"); n >= 0 {
+		p = p[n+3:]
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(p, "/"), ".git")
+}
+
+// tag returns tag name if origin ref is a tag.
+func (o *modOrigin) tag() string {
+	if !strings.HasPrefix(o.Ref, "refs/tags/") {
+		return ""
+	}
+	return strings.TrimPrefix(o.Ref, "refs/tags/")
+}
+
+// readModOrigin reads origin of module path at version from the module cache
+// in dir. Older toolchains don't record origin in .info files, module path
+// declared in .mod file is returned as the origin URL then, if it's different
+// from path. It returns nil if nothing useful is found.
+func readModOrigin(dir, path, version string) *modOrigin {
+	base := filepath.Join(dir, "cache", "download", filepath.FromSlash(escapeModulePath(path)), "@v", escapeModulePath(version))
+
+	if b, err := os.ReadFile(base + ".info"); err == nil {
+		var info struct {
+			Origin *modOrigin `json:"Origin"`
+		}
+		if err := json.Unmarshal(b, &info); err == nil && info.Origin != nil && info.Origin.URL != "" {
+			return info.Origin
+		}
+	}
+
+	if b, err := os.ReadFile(base + ".mod"); err == nil {
+		if mod := modFilePath(b); mod != "" && mod != path {
+			return &modOrigin{URL: mod}
+		}
+	}
+
+	return nil
+}
+
+// readModCacheTags returns tags of module path versions in the module cache
+// in dir whose origin commit ID starts with commit.
+func readModCacheTags(dir, path, commit string) []string {
+	infos, err := filepath.Glob(filepath.Join(dir, "cache", "download", filepath.FromSlash(escapeModulePath(path)), "@v", "*.info"))
+	if err != nil {
+		return nil
+	}
+
+	var res []string
+	for _, name := range infos {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		var info struct {
+			Origin *modOrigin `json:"Origin"`
+		}
+		if err := json.Unmarshal(b, &info); err != nil || info.Origin == nil || info.Origin.Hash == "" {
+			continue
+		}
+		if tag := info.Origin.tag(); tag != "" && strings.HasPrefix(info.Origin.Hash, commit) {
+			res = append(res, tag)
+		}
+	}
+
+	return res
+}
+
+// modFilePath returns module path declared in go.mod contents.
+func modFilePath(b []byte) string {
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) >= 2 && f[0] == "module" {
+			return strings.Trim(f[1], `"`)
+		}
+	}
+	return ""
+}
+
+// countModPackages returns the number of packages in sources of module path
+// at version extracted into the module cache in dir, 0 if they aren't there.
+// Test data, vendored packages and nested modules aren't counted.
+func countModPackages(dir, path, version string) int {
+	root := filepath.Join(dir, filepath.FromSlash(escapeModulePath(path))+"@"+escapeModulePath(version))
+
+	pkgs := map[string]bool{}
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p == root {
+				return nil
+			}
+			name := d.Name()
+			if name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if name := d.Name(); strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") {
+			pkgs[filepath.Dir(p)] = true
+		}
+		return nil
+	})
+
+	return len(pkgs)
+}
@@ -0,0 +1,120 @@
+package tuple
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModOrigin(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"go.etcd.io/etcd/client/v3/@v/v3.5.0.info": `{"Version":"v3.5.0","Origin":{"VCS":"git","URL":"https://github.com/etcd-io/etcd","Subdir":"client/v3","Ref":"refs/tags/client/v3/v3.5.0","Hash":"0123456789abcdef0123456789abcdef01234567"}}`,
+		"example.org/!lib/@v/v1.2.3.info":          `{"Version":"v1.2.3","Origin":{"VCS":"git","URL":"https://gitlab.com/group/lib.git","Ref":"refs/tags/v1.2.3","Hash":"89abcdef0123456789abcdef0123456789abcdef"}}`,
+		"example.org/old/@v/v1.0.0.info":           `{"Version":"v1.0.0","Time":"2020-01-01T00:00:00Z"}`,
+		"example.org/old/@v/v1.0.0.mod":            "module github.com/new/old\n\ngo 1.16\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, "cache", "download", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	examples := [][]string{
+		// spec, expected String() after Fix
+		{"go.etcd.io/etcd/client/v3 v3.5.0", "etcd-io:etcd:0123456789ab:etcd_io_etcd_v3/vendor/go.etcd.io/etcd"},
+		{"example.org/Lib v1.2.3", "group:lib:89abcdef0123456789abcdef0123456789abcdef:group_lib/vendor/example.org/Lib"},
+		{"example.org/old v1.0.0", "new:old:v1.0.0:new_old/vendor/example.org/old"},
+		// no origin
+		{"github.com/pkg/errors v0.9.1", "pkg:errors:v0.9.1:pkg_errors/vendor/github.com/pkg/errors"},
+	}
+
+	opts := &Options{Offline: true, ModCache: dir}
+	for i, x := range examples {
+		tuple, err := Parse(context.Background(), x[0], opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tuple.Fix(context.Background(), opts); err != nil {
+			t.Fatal(err)
+		}
+		if s := tuple.String(); s != x[1] {
+			t.Errorf("(%d) expected String() to return %q, got %q", i, x[1], s)
+		}
+	}
+
+	// origins replace API lookups only offline
+	online := &Options{ModCache: dir}
+	if o := online.modOrigin("go.etcd.io/etcd/client/v3", "v3.5.0"); o != nil {
+		t.Errorf("expected module origin to be ignored online, got %+v", *o)
+	}
+}
+
+func TestPreferTags(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"github.com/account/project/@v/v1.2.3.info":     `{"Version":"v1.2.3","Origin":{"VCS":"git","URL":"https://github.com/account/project","Ref":"refs/tags/v1.2.3","Hash":"0123456789abcdef0123456789abcdef01234567"}}`,
+		"github.com/account/project/@v/v1.2.2.info":     `{"Version":"v1.2.2","Origin":{"VCS":"git","URL":"https://github.com/account/project","Ref":"refs/tags/v1.2.2","Hash":"89abcdef0123456789abcdef0123456789abcdef"}}`,
+		"github.com/account/project/api/@v/v0.1.0.info": `{"Version":"v0.1.0","Origin":{"VCS":"git","URL":"https://github.com/account/project","Subdir":"api","Ref":"refs/tags/api/v0.1.0","Hash":"fedcba9876543210fedcba9876543210fedcba98"}}`,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, "cache", "download", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	examples := [][]string{
+		// spec, expected version after Fix
+		{"github.com/account/project v1.2.4-0.20200101000000-0123456789ab", "v1.2.3"},
+		{"github.com/account/project/api v0.1.1-0.20200101000000-fedcba987654", "api/v0.1.0"},
+		// no tag at this commit
+		{"github.com/account/project v1.2.4-0.20200101000000-456789abcdef", "456789abcdef"},
+		// not a pseudo-version
+		{"github.com/account/project v1.2.2", "v1.2.2"},
+	}
+
+	opts := &Options{Offline: true, ModCache: dir, PreferTags: true}
+	for i, x := range examples {
+		tuple, err := Parse(context.Background(), x[0], opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tuple.Fix(context.Background(), opts); err != nil {
+			t.Fatal(err)
+		}
+		if tuple.version != x[1] {
+			t.Errorf("(%d) expected version to be %q, got %q", i, x[1], tuple.version)
+		}
+	}
+}
+
+func TestPreferredTag(t *testing.T) {
+	examples := []struct {
+		tags   []string
+		module string
+		tag    string
+	}{
+		{[]string{"v1.2.3-rc.1", "v1.2.3"}, "", "v1.2.3"},
+		{[]string{"v1.2.3", "api/v1.2.3"}, "api", "api/v1.2.3"},
+		{[]string{"other/v1.2.3", "v1.2.3"}, "api", "v1.2.3"},
+		{[]string{"other/v1.2.3"}, "", ""},
+		{nil, "", ""},
+	}
+
+	for i, x := range examples {
+		if tag := preferredTag(x.tags, x.module); tag != x.tag {
+			t.Errorf("(%d) expected tag %q, got %q", i, x.tag, tag)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package tuple
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GoModules returns GO_MODULES variable contents, listing Go modules to be fetched
+// from the Go module proxy as "module:version" entries. Replaced modules have
+// their required module path appended as "module:version:path". Modules replaced
+// by local directories are skipped.
+func (s Slice) GoModules() string {
+	var entries []string
+
+	seen := map[string]bool{}
+	for _, t := range s {
+		if t.modVersion == "" || isFilesystemPath(t.link_tgt) {
+			continue
+		}
+		e := t.goModule()
+		if !seen[e] {
+			entries = append(entries, e)
+			seen[e] = true
+		}
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	sort.Strings(entries)
+
+	return formatVar("GO_MODULES=", entries)
+}
+
+func (t *Tuple) goModule() string {
+	res := fmt.Sprintf("%s:%s", t.pkg, t.modVersion)
+	if t.modPath != t.pkg {
+		res = fmt.Sprintf("%s:%s", res, t.modPath)
+	}
+	return res
+}
+
+// VersionComments returns Makefile comments listing the original Go module
+// path and version of each tuple, along with the go version the module
+// declares, if known.
+func (s Slice) VersionComments() string {
+	var entries []string
+
+	seen := map[string]bool{}
+	for _, t := range s {
+		if t.modVersion == "" {
+			continue
+		}
+		e := fmt.Sprintf("\t\t#\t%s: %s %s", t.group, t.modPath, t.modVersion)
+		if t.goVersion != "" {
+			e = fmt.Sprintf("%s (go %s)", e, t.goVersion)
+		}
+		if !seen[e] {
+			entries = append(entries, e)
+			seen[e] = true
+		}
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	sort.Strings(entries)
+
+	return "\t\t# Go module versions:\n" + strings.Join(entries, "\n")
+}
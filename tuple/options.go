@@ -0,0 +1,118 @@
+package tuple
+
+import (
+	"github.com/dmgk/modules2tuple/v2/apis"
+	"github.com/dmgk/modules2tuple/v2/config"
+	"github.com/dmgk/modules2tuple/v2/debug"
+)
+
+// Options control how tuples are resolved and fixed. Nil *Options is the same
+// as zero Options: network access is allowed, debug output is discarded and
+// APIs are called using zero apis.Client.
+type Options struct {
+	// Offline disables all network access.
+	Offline bool
+	// Logger receives debug messages, if set.
+	Logger debug.Logger
+	// Client is used to call Github and Gitlab APIs and to discover vanity
+	// import mirrors. It also carries API credentials.
+	Client *apis.Client
+	// ModCache is the Go module cache directory (GOMODCACHE) module origins
+	// are read from in offline mode, disabled if empty.
+	ModCache string
+	// PreferTags replaces Github commit IDs of pseudo-versions with tags
+	// pointing at the same commit, if there are any.
+	PreferTags bool
+	// GoProxy is the Go module proxy URL modules with unknown mirrors are
+	// fetched from, proxy fallback is disabled if empty.
+	GoProxy string
+	// SkipUnused skips modules.txt modules that don't provide any vendored
+	// packages instead of resolving them.
+	SkipUnused bool
+	// Mirrors are user-defined mirrors and aliases loaded from the mirrors
+	// file, if any.
+	Mirrors *Mirrors
+}
+
+// DefaultOptions returns Options configured from the command line settings.
+func DefaultOptions() *Options {
+	o := &Options{
+		Offline:    config.Offline,
+		Client:     apis.NewClient(),
+		ModCache:   config.ModCache,
+		PreferTags: config.PreferTags,
+		SkipUnused: config.SkipUnused,
+	}
+	if config.Debug {
+		o.Logger = debug.Stderr
+	}
+	if config.ProxyFallback {
+		o.GoProxy = config.GoProxy
+	}
+	return o
+}
+
+func (o *Options) offline() bool {
+	return o != nil && o.Offline
+}
+
+func (o *Options) debug() bool {
+	return o != nil && o.Logger != nil
+}
+
+// Logf prints debug message using o.Logger, if set.
+func (o *Options) Logf(format string, v ...interface{}) {
+	if o.debug() {
+		o.Logger.Printf(format, v...)
+	}
+}
+
+func (o *Options) goProxy() (ProxySource, bool) {
+	if o == nil || o.GoProxy == "" {
+		return "", false
+	}
+	return ProxySource(o.GoProxy), true
+}
+
+// modOrigin returns origin of module path at version from the module cache,
+// nil if it's unknown. Origins are only used offline, online lookups are
+// left to the APIs.
+func (o *Options) modOrigin(path, version string) *modOrigin {
+	if !o.offline() || o.ModCache == "" || version == "" {
+		return nil
+	}
+	origin := readModOrigin(o.ModCache, path, version)
+	if origin != nil {
+		o.Logf("[modOrigin] %s@%s origin: %+v\n", path, version, *origin)
+	}
+	return origin
+}
+
+func (o *Options) preferTags() bool {
+	return o != nil && o.PreferTags
+}
+
+// modCacheTags returns tags of module path versions in the module cache
+// pointing at commit.
+func (o *Options) modCacheTags(path, commit string) []string {
+	if o == nil || o.ModCache == "" {
+		return nil
+	}
+	return readModCacheTags(o.ModCache, path, commit)
+}
+
+// modPackages returns the number of packages in module path at version
+// extracted into the module cache, 0 if it's unknown.
+func (o *Options) modPackages(path, version string) int {
+	if o == nil || o.ModCache == "" || version == "" {
+		return 0
+	}
+	return countModPackages(o.ModCache, path, version)
+}
+
+func (o *Options) client() *apis.Client {
+	if o == nil || o.Client == nil {
+		return &apis.Client{}
+	}
+	return o.Client
+}
@@ -0,0 +1,149 @@
+package tuple
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SetPackages records import paths of packages vendored from the tuple module,
+// as listed in modules.txt. Total number of module packages is counted in the
+// module cache, if module sources are extracted there.
+func (t *Tuple) SetPackages(pkgs []string, opts *Options) {
+	t.packages = append([]string(nil), pkgs...)
+	sort.Strings(t.packages)
+	if len(t.packages) > 0 && !isFilesystemPath(t.link_tgt) {
+		t.modPackages = opts.modPackages(t.pkg, t.modVersion)
+	}
+}
+
+// Packages returns import paths of vendored packages, if known.
+func (t *Tuple) Packages() []string {
+	return append([]string(nil), t.packages...)
+}
+
+// ModulePackages returns the number of packages in the module, 0 if unknown.
+func (t *Tuple) ModulePackages() int {
+	return t.modPackages
+}
+
+// Unused returns an estimate of the fraction of module packages that aren't
+// vendored, -1 if it's unknown.
+func (t *Tuple) Unused() float64 {
+	if t.modPackages == 0 || len(t.packages) == 0 {
+		return -1
+	}
+	if len(t.packages) >= t.modPackages {
+		return 0
+	}
+	return 1 - float64(len(t.packages))/float64(t.modPackages)
+}
+
+// packageDirs returns vendored package directories relative to the module
+// directory, "" for the module root.
+func (t *Tuple) packageDirs() []string {
+	var res []string
+	for _, p := range t.packages {
+		if p == t.modPath {
+			res = append(res, "")
+		} else if strings.HasPrefix(p, t.modPath+"/") {
+			res = append(res, strings.TrimPrefix(p, t.modPath+"/"))
+		}
+	}
+	return res
+}
+
+// PackageReport returns vendored packages of each tuple along with an estimate
+// of how much of the module is unused. Tuples without known packages are skipped.
+func (s Slice) PackageReport() string {
+	var tuples Slice
+	for _, t := range s {
+		if len(t.packages) > 0 {
+			tuples = append(tuples, t)
+		}
+	}
+	sort.Slice(tuples, func(i, j int) bool {
+		return tuples[i].group < tuples[j].group
+	})
+
+	var entries []string
+	for _, t := range tuples {
+		var b bytes.Buffer
+		b.WriteString(fmt.Sprintf("%s: %s %s, vendored packages: %d", t.group, t.modPath, t.modVersion, len(t.packages)))
+		if u := t.Unused(); u >= 0 {
+			b.WriteString(fmt.Sprintf(" of %d (~%.0f%% unused)", t.modPackages, u*100))
+		}
+		for _, p := range t.packages {
+			b.WriteString("\n\t" + p)
+		}
+		entries = append(entries, b.String())
+	}
+
+	return strings.Join(entries, "\n")
+}
+
+// cleanup returns post-extract recipe lines removing files outside of vendored
+// package directories (and their subdirectories) from vendored module
+// directories. Files in module root, directories of other modules nested
+// in the module directory and linked modules are left alone. Modules without
+// known package directories or with the root package vendored are kept whole,
+// the root package may embed files from anywhere in the module.
+func (s Slice) cleanup() []string {
+	linkSources := map[*Tuple]bool{}
+	for _, t := range s {
+		if t.link_src != nil {
+			linkSources[t.link_src] = true
+		}
+	}
+
+	var tuples Slice
+	for _, t := range s {
+		if len(t.packages) > 0 && !t.isLinked() && !linkSources[t] {
+			tuples = append(tuples, t)
+		}
+	}
+	sort.Slice(tuples, func(i, j int) bool {
+		return tuples[i].modPath < tuples[j].modPath
+	})
+
+	var lines []string
+	for _, t := range tuples {
+		dir := filepath.Join("${WRKSRC}", "vendor", t.modPath)
+
+		pkgDirs := t.packageDirs()
+		if len(pkgDirs) == 0 {
+			continue
+		}
+		keep := map[string]bool{}
+		for _, d := range pkgDirs {
+			keep[d] = true
+		}
+		if keep[""] {
+			continue
+		}
+		for _, o := range s {
+			if o != t && strings.HasPrefix(o.modPath, t.modPath+"/") {
+				keep[strings.TrimPrefix(o.modPath, t.modPath+"/")] = true
+			}
+		}
+		dirs := make([]string, 0, len(keep))
+		for d := range keep {
+			dirs = append(dirs, d)
+		}
+		sort.Strings(dirs)
+
+		var b bytes.Buffer
+		b.WriteString(fmt.Sprintf("\t@${FIND} %s -mindepth 2 -type f", dir))
+		for _, d := range dirs {
+			b.WriteString(fmt.Sprintf(" ! -path '%s/*'", filepath.Join(dir, d)))
+		}
+		b.WriteString(" -delete\n")
+		b.WriteString(fmt.Sprintf("\t@${FIND} %s -mindepth 1 -type d -empty -delete", dir))
+
+		lines = append(lines, b.String())
+	}
+
+	return lines
+}
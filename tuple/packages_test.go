@@ -0,0 +1,41 @@
+package tuple
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCleanup(t *testing.T) {
+	examples := []struct {
+		modPath  string
+		packages []string
+		expected string
+	}{
+		{
+			"example.org/account/project",
+			[]string{"example.org/account/project/api", "example.org/account/project/internal/util"},
+			"\t@${FIND} ${WRKSRC}/vendor/example.org/account/project -mindepth 2 -type f ! -path '${WRKSRC}/vendor/example.org/account/project/api/*' ! -path '${WRKSRC}/vendor/example.org/account/project/internal/util/*' -delete\n" +
+				"\t@${FIND} ${WRKSRC}/vendor/example.org/account/project -mindepth 1 -type d -empty -delete",
+		},
+		// root package may embed files from anywhere in the module
+		{
+			"example.org/account/project",
+			[]string{"example.org/account/project", "example.org/account/project/api"},
+			"",
+		},
+		// no package directory in the module
+		{
+			"example.org/account/project",
+			[]string{"example.org/account/other"},
+			"",
+		},
+	}
+
+	for i, x := range examples {
+		s := Slice{{modPath: x.modPath, packages: x.packages}}
+		out := strings.Join(s.cleanup(), "\n")
+		if out != x.expected {
+			t.Errorf("(%d) expected cleanup\n%q\n, got\n%q\n", i, x.expected, out)
+		}
+	}
+}
@@ -1,22 +1,31 @@
 package tuple
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
-
-	"github.com/dmgk/modules2tuple/v2/config"
-	"github.com/dmgk/modules2tuple/v2/debug"
 )
 
+// SourceError is returned along with the unresolved tuple when package
+// mirror can't be resolved.
 type SourceError string
 
 func (err SourceError) Error() string {
 	return string(err)
 }
 
-// Resolve looks up mirrors and parses tuple account and project.
-func Resolve(pkg, version, subdir, link_target string) (*Tuple, error) {
+// maxDiscoveryHops limits how many vanity hosts are followed during mirror discovery.
+const maxDiscoveryHops = 3
+
+// Resolve looks up mirrors and parses tuple account and project. If the mirror
+// can't be resolved, SourceError is returned along with the unresolved tuple.
+func Resolve(ctx context.Context, pkg, version, subdir, link_target string, opts *Options) (*Tuple, error) {
+	return resolve(ctx, pkg, version, subdir, link_target, nil, opts)
+}
+
+// resolve is Resolve that also uses module origin recorded in the module cache, if any.
+func resolve(ctx context.Context, pkg, version, subdir, link_target string, origin *modOrigin, opts *Options) (*Tuple, error) {
 	t := &Tuple{
 		pkg:      pkg,
 		version:  version,
@@ -25,37 +34,96 @@ func Resolve(pkg, version, subdir, link_target string) (*Tuple, error) {
 		group:    "group_name",
 	}
 
-	var done bool
+	// mirror of the origin repository, module is origin subdir
+	var om *mirror
+	if origin != nil {
+		m, err := opts.lookupMirror(origin.repoPath())
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			om = &mirror{m.source, m.account, m.project, origin.Subdir}
+		}
+	}
+
+	// discovered repositories, go-import may point to another vanity host
+	seen := map[string]bool{}
 	for {
-		// try static mirror lookup first
-		for _, r := range resolvers {
-			if strings.HasPrefix(pkg, r.prefix) {
-				m, err := r.resolver.resolve(pkg)
-				if err != nil {
-					return nil, err
-				}
-				if m != nil {
-					t.makeResolved(m.source, m.account, m.project, m.module)
-					return t, nil
-				}
+		// try user-defined and static mirror lookup first
+		m, err := opts.lookupMirror(pkg)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			t.makeResolved(m.source, m.account, m.project, m.module)
+			if om != nil && sameRepo(m, om) {
+				t.origin = origin
 			}
+			return t, nil
 		}
 
-		if done || config.Offline {
+		// then the origin, it doesn't need network access
+		if om != nil {
+			opts.Logf("[tuple.Resolve] using module cache origin %q for %q\n", origin.URL, pkg)
+			t.makeResolved(om.source, om.account, om.project, om.module)
+			t.origin = origin
+			return t, nil
+		}
+
+		if opts.offline() || seen[pkg] || len(seen) >= maxDiscoveryHops {
 			break
 		}
+		seen[pkg] = true
 
 		// try looking up missing mirror online
-		m, err := discoverMirrors(pkg)
+		repo, err := discoverMirrors(ctx, pkg, opts)
 		if err != nil {
 			return nil, err
 		}
-		debug.Printf("[tuple.Resolve] discovered mirror %q for %q\n", m, pkg)
-		pkg = m
-		done = true
+		if repo == "" {
+			break
+		}
+		opts.Logf("[tuple.Resolve] discovered mirror %q for %q\n", repo, pkg)
+		pkg = repo
 	}
 
-	return nil, SourceError(fmt.Sprintf("%s (from %s@%s)", t.String(), pkg, version))
+	return t, SourceError(fmt.Sprintf("%s (from %s@%s)", t.String(), pkg, version))
+}
+
+// sameRepo reports whether mirrors point to the same repository.
+func sameRepo(a, b *mirror) bool {
+	return a.source == b.source && strings.EqualFold(a.account, b.account) && strings.EqualFold(a.project, b.project)
+}
+
+// lookupMirror looks up pkg mirror using user-defined mirrors first, then
+// aliases and the built-in mirrors.
+func (o *Options) lookupMirror(pkg string) (*mirror, error) {
+	if o != nil && o.Mirrors != nil {
+		m, err := resolvePrefix(o.Mirrors.resolvers, pkg)
+		if m != nil || err != nil {
+			return m, err
+		}
+	}
+	if a, ok := o.lookupAlias(pkg); ok {
+		return &mirror{GH, a.account, a.project, ""}, nil
+	}
+	return resolvePrefix(resolvers, pkg)
+}
+
+// resolvePrefix resolves pkg using the first matching resolver in rr.
+func resolvePrefix(rr []prefixResolver, pkg string) (*mirror, error) {
+	for _, r := range rr {
+		if strings.HasPrefix(pkg, r.prefix) {
+			m, err := r.resolver.resolve(pkg)
+			if err != nil {
+				return nil, err
+			}
+			if m != nil {
+				return m, nil
+			}
+		}
+	}
+	return nil, nil
 }
 
 type mirror struct {
@@ -79,15 +147,17 @@ func (f mirrorFn) resolve(pkg string) (*mirror, error) {
 	return f(pkg)
 }
 
-var resolvers = []struct {
+type prefixResolver struct {
 	prefix   string
 	resolver resolver
-}{
-	// Docker is a special snowflake
-	{"github.com/docker/docker", &mirror{GH, "moby", "moby", ""}},
+}
 
+var resolvers = []prefixResolver{
 	{"github.com", mirrorFn(githubResolver)},
 	{"gitlab.com", mirrorFn(gitlabResolver)},
+	{"git.sr.ht", mirrorFn(sourcehutResolver)},
+	{"codeberg.org", mirrorFn(codebergResolver)},
+	{"bitbucket.org", mirrorFn(bitbucketResolver)},
 
 	{"contrib.go.opencensus.io/exporter/ocagent", &mirror{GH, "census-ecosystem", "opencensus-go-exporter-ocagent", ""}},
 	{"aletheia.icu/broccoli/fs", &mirror{GH, "aletheia-icu", "broccoli", "fs"}},
@@ -156,6 +226,51 @@ func gitlabResolver(pkg string) (*mirror, error) {
 	return &mirror{GL, parts[1], parts[2], module}, nil
 }
 
+func sourcehutResolver(pkg string) (*mirror, error) {
+	if !strings.HasPrefix(pkg, "git.sr.ht/") {
+		return nil, nil
+	}
+	parts := strings.SplitN(pkg, "/", 4)
+	if len(parts) < 3 || !strings.HasPrefix(parts[1], "~") {
+		return nil, fmt.Errorf("unexpected SourceHut package name: %q", pkg)
+	}
+	var module string
+	if len(parts) == 4 {
+		module = parts[3]
+	}
+	return &mirror{SH, strings.TrimPrefix(parts[1], "~"), parts[2], module}, nil
+}
+
+func codebergResolver(pkg string) (*mirror, error) {
+	if !strings.HasPrefix(pkg, "codeberg.org/") {
+		return nil, nil
+	}
+	parts := strings.SplitN(pkg, "/", 4)
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("unexpected Codeberg package name: %q", pkg)
+	}
+	var module string
+	if len(parts) == 4 {
+		module = parts[3]
+	}
+	return &mirror{CB, parts[1], parts[2], module}, nil
+}
+
+func bitbucketResolver(pkg string) (*mirror, error) {
+	if !strings.HasPrefix(pkg, "bitbucket.org/") {
+		return nil, nil
+	}
+	parts := strings.SplitN(pkg, "/", 4)
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("unexpected Bitbucket package name: %q", pkg)
+	}
+	var module string
+	if len(parts) == 4 {
+		module = parts[3]
+	}
+	return &mirror{BB, parts[1], parts[2], module}, nil
+}
+
 // bazil.org/fuse -> github.com/bazil/fuse
 var bazilOrgRe = regexp.MustCompile(`\Abazil\.org/([0-9A-Za-z][-0-9A-Za-z]+)\z`)
 
@@ -279,10 +394,6 @@ func gopkgInResolver(pkg string) (*mirror, error) {
 	if !gopkgInRe.MatchString(pkg) {
 		return nil, nil
 	}
-	// fsnotify is a special case in gopkg.in
-	if pkg == "gopkg.in/fsnotify.v1" {
-		return &mirror{GH, "fsnotify", "fsnotify", ""}, nil
-	}
 	sm := gopkgInRe.FindAllStringSubmatch(pkg, -1)
 	if len(sm) == 0 {
 		return nil, nil
@@ -1,16 +1,11 @@
 package tuple
 
 import (
+	"context"
 	"fmt"
 	"testing"
-
-	"github.com/dmgk/modules2tuple/v2/config"
 )
 
-func init() {
-	config.Offline = true
-}
-
 type resolverExample struct {
 	pkg                      string
 	source                   Source
@@ -71,6 +66,73 @@ func TestGitlabResolver(t *testing.T) {
 	}
 }
 
+func TestSourcehutResolver(t *testing.T) {
+	examples := []resolverExample{
+		{"git.sr.ht/~sircmpwn/getopt", SH, "sircmpwn", "getopt", ""},
+		{"git.sr.ht/~emersion/go-scfg/cmd", SH, "emersion", "go-scfg", "cmd"},
+	}
+
+	for i, x := range examples {
+		m, err := sourcehutResolver(x.pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m == nil {
+			t.Fatalf("(%d): expected %q to match", i, x.pkg)
+		}
+		if fmt.Sprintf("%T %v", m.source, m.source) != fmt.Sprintf("%T %v", x.source, x.source) {
+			t.Errorf("(%d) expected source to be %q, got %q", i, fmt.Sprintf("%T %v", x.source, x.source), fmt.Sprintf("%T %v", m.source, m.source))
+		}
+		if m.account != x.account {
+			t.Errorf("(%d) expected account to be %q, got %q", i, x.account, m.account)
+		}
+		if m.project != x.project {
+			t.Errorf("(%d) expected project to be %q, got %q", i, x.project, m.project)
+		}
+		if m.module != x.module {
+			t.Errorf("(%d) expected module to be %q, got %q", i, x.module, m.module)
+		}
+	}
+}
+
+func TestCodebergResolver(t *testing.T) {
+	examples := []resolverExample{
+		{"codeberg.org/account/project", CB, "account", "project", ""},
+		{"codeberg.org/account/project/v2", CB, "account", "project", "v2"},
+	}
+
+	for i, x := range examples {
+		m, err := codebergResolver(x.pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m == nil {
+			t.Fatalf("(%d): expected %q to match", i, x.pkg)
+		}
+		if fmt.Sprintf("%T %v", m.source, m.source) != fmt.Sprintf("%T %v", x.source, x.source) {
+			t.Errorf("(%d) expected source to be %q, got %q", i, fmt.Sprintf("%T %v", x.source, x.source), fmt.Sprintf("%T %v", m.source, m.source))
+		}
+		if m.account != x.account {
+			t.Errorf("(%d) expected account to be %q, got %q", i, x.account, m.account)
+		}
+		if m.project != x.project {
+			t.Errorf("(%d) expected project to be %q, got %q", i, x.project, m.project)
+		}
+		if m.module != x.module {
+			t.Errorf("(%d) expected module to be %q, got %q", i, x.module, m.module)
+		}
+	}
+}
+
+func TestBitbucketResolver(t *testing.T) {
+	examples := []resolverExample{
+		// name, expected account, expected project
+		{"bitbucket.org/account/project", BB, "account", "project", ""},
+		{"bitbucket.org/account/project/subpkg", BB, "account", "project", "subpkg"},
+	}
+	testResolverFnExamples(t, "bitbucketResolver", bitbucketResolver, examples)
+}
+
 func TestMirrorResolver(t *testing.T) {
 	examples := []resolverExample{
 		{"camlistore.org", GH, "perkeep", "perkeep", ""},
@@ -96,7 +158,7 @@ func TestMirrorResolver(t *testing.T) {
 	}
 
 	for i, x := range examples {
-		m, err := Resolve(x.pkg, "", "", "")
+		m, err := Resolve(context.Background(), x.pkg, "", "", "", offlineOptions)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -1,5 +1,12 @@
 package tuple
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dmgk/modules2tuple/v2/apis"
+)
+
 type Source interface {
 	String() string
 }
@@ -16,12 +23,140 @@ func (s GitlabSource) String() string {
 	return string(s)
 }
 
+// SourcehutSource is SourceHut git site, empty for https://git.sr.ht.
+// Its tuples are fetched as regular distfiles.
+type SourcehutSource string
+
+func (s SourcehutSource) String() string {
+	return string(s)
+}
+
+func (s SourcehutSource) site() string {
+	if s == "" {
+		return apis.DefaultSourcehutURL
+	}
+	return strings.TrimSuffix(string(s), "/")
+}
+
+func (s SourcehutSource) archiveURL(t *Tuple) string {
+	return fmt.Sprintf("%s/~%s/%s/archive/%s.tar.gz", s.site(), t.account, t.project, t.version)
+}
+
+func (s SourcehutSource) archiveDir(t *Tuple) string {
+	return t.project + "-" + t.version
+}
+
+// GiteaSource is Gitea or Forgejo site, empty for https://codeberg.org.
+// Its tuples are fetched as regular distfiles.
+type GiteaSource string
+
+func (s GiteaSource) String() string {
+	return string(s)
+}
+
+func (s GiteaSource) site() string {
+	if s == "" {
+		return apis.DefaultGiteaURL
+	}
+	return strings.TrimSuffix(string(s), "/")
+}
+
+func (s GiteaSource) archiveURL(t *Tuple) string {
+	return fmt.Sprintf("%s/%s/%s/archive/%s.tar.gz", s.site(), t.account, t.project, t.version)
+}
+
+func (s GiteaSource) archiveDir(t *Tuple) string {
+	return t.project
+}
+
+// BitbucketSource is Bitbucket Cloud, its tuples are fetched as regular distfiles.
+type BitbucketSource string
+
+func (s BitbucketSource) String() string {
+	return string(s)
+}
+
+func (s BitbucketSource) archiveURL(t *Tuple) string {
+	return fmt.Sprintf("https://bitbucket.org/%s/%s/get/%s.tar.gz", t.account, t.project, t.version)
+}
+
+// archiveDir returns "account-project-commit" directory name Bitbucket uses,
+// with the commit ID shortened to 12 characters.
+func (s BitbucketSource) archiveDir(t *Tuple) string {
+	version := t.version
+	if len(version) > 12 {
+		version = version[:12]
+	}
+	return t.account + "-" + t.project + "-" + version
+}
+
+// DefaultGoProxy is the public Go module proxy URL.
+const DefaultGoProxy = "https://proxy.golang.org"
+
+// ProxySource is Go module proxy URL, empty for DefaultGoProxy. Its tuples
+// are module zip files fetched as regular distfiles, tuple version is the
+// full module version.
+type ProxySource string
+
+func (s ProxySource) String() string {
+	return string(s)
+}
+
+func (s ProxySource) site() string {
+	if s == "" {
+		return DefaultGoProxy
+	}
+	return strings.TrimSuffix(string(s), "/")
+}
+
+func (s ProxySource) archiveURL(t *Tuple) string {
+	return fmt.Sprintf("%s/%s/@v/%s.zip", s.site(), escapeModulePath(t.pkg), escapeModulePath(t.version))
+}
+
+// archiveDir returns "module@version" directory all module zip files are prefixed with.
+func (s ProxySource) archiveDir(t *Tuple) string {
+	return t.pkg + "@" + t.version
+}
+
+// escapeModulePath escapes upper-case letters in module path or version as
+// "!" followed by the lower-case letter, as the module proxy protocol requires.
+func escapeModulePath(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if 'A' <= r && r <= 'Z' {
+			b.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// distfileSource is a source not supported by bsd.sites.mk, its tuples are
+// added to MASTER_SITES and DISTFILES directly and moved in place in post-extract.
+type distfileSource interface {
+	Source
+	// archiveURL returns URL of the tuple archive.
+	archiveURL(t *Tuple) string
+	// archiveDir returns top-level directory of the tuple archive.
+	archiveDir(t *Tuple) string
+}
+
 // GH is Github default source
 var GH GithubSource
 
 // GL is Gitlab default source
 var GL GitlabSource
 
+// SH is SourceHut default source
+var SH SourcehutSource
+
+// CB is Codeberg, the default Gitea source
+var CB GiteaSource
+
+// BB is Bitbucket source
+var BB BitbucketSource
+
 func sourceVarName(s Source) string {
 	switch s.(type) {
 	case GithubSource:
@@ -32,3 +167,42 @@ func sourceVarName(s Source) string {
 		panic("unknown source type")
 	}
 }
+
+// distfileSuffix returns the suffix bsd.sites.mk appends to the distfile name.
+func distfileSuffix(s Source) string {
+	switch s.(type) {
+	case GithubSource:
+		return "_GH0.tar.gz"
+	case GitlabSource:
+		return "_GL0.tar.gz"
+	case SourcehutSource:
+		return "_SH0.tar.gz"
+	case GiteaSource:
+		return "_GT0.tar.gz"
+	case BitbucketSource:
+		return "_BB0.tar.gz"
+	case ProxySource:
+		return "_GP0.zip"
+	default:
+		panic("unknown source type")
+	}
+}
+
+func sourceName(s Source) string {
+	switch s.(type) {
+	case GithubSource:
+		return "github"
+	case GitlabSource:
+		return "gitlab"
+	case SourcehutSource:
+		return "sourcehut"
+	case GiteaSource:
+		return "gitea"
+	case BitbucketSource:
+		return "bitbucket"
+	case ProxySource:
+		return "goproxy"
+	default:
+		return ""
+	}
+}
@@ -2,20 +2,20 @@ package tuple
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
-
-	"github.com/dmgk/modules2tuple/v2/apis"
-	"github.com/dmgk/modules2tuple/v2/config"
-	"github.com/dmgk/modules2tuple/v2/debug"
+	"time"
 )
 
-// Parse parses a package spec into Tuple.
-func Parse(spec string) (*Tuple, error) {
+// Parse parses a package spec into Tuple. If the package mirror can't be
+// resolved, SourceError is returned along with the unresolved tuple.
+func Parse(ctx context.Context, spec string, opts *Options) (*Tuple, error) {
 	const replaceSep = " => "
 
 	// "replace" spec
@@ -38,10 +38,14 @@ func Parse(spec string) (*Tuple, error) {
 		// https://github.com/golang/go/wiki/Modules#when-should-i-use-the-replace-directive
 		if isFilesystemPath(rightPkg) {
 			// get the left spec package and symlink it to the rightPkg path
-			return Resolve(leftPkg, leftVersion, leftPkg, rightPkg)
+			origin := opts.modOrigin(leftPkg, fullVersion(parts[0]))
+			t, err := resolve(ctx, leftPkg, leftVersion, leftPkg, rightPkg, origin, opts)
+			return withModule(t, err, leftPkg, fullVersion(parts[0]), opts)
 		}
 		// get the right spec package and put it under leftPkg path
-		return Resolve(rightPkg, rightVersion, leftPkg, "")
+		origin := opts.modOrigin(rightPkg, fullVersion(parts[1]))
+		t, err := resolve(ctx, rightPkg, rightVersion, leftPkg, "", origin, opts)
+		return withModule(t, err, leftPkg, fullVersion(parts[1]), opts)
 	}
 
 	// regular spec
@@ -49,7 +53,86 @@ func Parse(spec string) (*Tuple, error) {
 	if err != nil {
 		return nil, err
 	}
-	return Resolve(pkg, version, pkg, "")
+	origin := opts.modOrigin(pkg, fullVersion(spec))
+	t, err := resolve(ctx, pkg, version, pkg, "", origin, opts)
+	return withModule(t, err, pkg, fullVersion(spec), opts)
+}
+
+// withModule records Go module path and version on the tuple returned by Resolve,
+// including unresolved tuple returned with SourceError. Unresolved tuple is
+// fetched from the Go module proxy instead, if opts enable proxy fallback.
+func withModule(t *Tuple, err error, path, version string, opts *Options) (*Tuple, error) {
+	if _, ok := err.(SourceError); ok && t != nil {
+		t.modPath = path
+		t.setModVersion(version)
+		if proxy, ok := opts.goProxy(); ok && version != "" {
+			t.makeProxied(proxy)
+			opts.Logf("[tuple.Parse] fetching %s@%s from the module proxy\n", t.pkg, version)
+			return t, nil
+		}
+		return t, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.modPath = path
+	t.setModVersion(version)
+	return t, nil
+}
+
+// fullVersion returns unmodified module version from the package spec.
+func fullVersion(spec string) string {
+	parts := strings.Fields(spec)
+	if len(parts) != 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// ParseTuple parses G{H,L}_TUPLE entry "[site:]account:project:version:group[/subdir]"
+// of the variable varName. Go module path of the tuple is derived from its vendor subdir.
+func ParseTuple(varName, entry string) (*Tuple, error) {
+	var source Source
+	switch varName {
+	case "GH_TUPLE":
+		source = GH
+	case "GL_TUPLE":
+		source = GL
+	default:
+		return nil, fmt.Errorf("unexpected tuple variable: %q", varName)
+	}
+
+	s := entry
+	if _, ok := source.(GitlabSource); ok && schemeRe.MatchString(s) {
+		scheme := schemeRe.FindString(s)
+		n := strings.IndexByte(s[len(scheme):], ':')
+		if n < 0 {
+			return nil, fmt.Errorf("unexpected %s entry: %q", varName, entry)
+		}
+		source = GitlabSource(s[:len(scheme)+n])
+		s = s[len(scheme)+n+1:]
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("unexpected %s entry: %q", varName, entry)
+	}
+
+	t := &Tuple{
+		source:  source,
+		account: parts[0],
+		project: parts[1],
+		version: parts[2],
+		group:   parts[3],
+	}
+	if n := strings.IndexByte(parts[3], '/'); n >= 0 {
+		t.group = parts[3][:n]
+		t.subdir = strings.TrimPrefix(parts[3][n+1:], "vendor/")
+		t.pkg = t.subdir
+		t.modPath = t.subdir
+	}
+
+	return t, nil
 }
 
 // v1.0.0
@@ -88,21 +171,135 @@ func parseSpec(spec string) (string, string, error) {
 }
 
 func isFilesystemPath(s string) bool {
-	return s[0] == '.' || s[0] == '/'
+	return s != "" && (s[0] == '.' || s[0] == '/')
 }
 
 type Tuple struct {
-	pkg      string // Go package name
-	version  string // tag or commit ID
-	subdir   string // GH_TUPLE subdir
-	group    string // GH_TUPLE group
-	module   string // module, if any
-	link_src *Tuple // symlink source tuple, if any
-	link_tgt string // symlink target, if any
-	source   Source // tuple source (Github ot Gitlab)
-	account  string // source account
-	project  string // source project
-	hidden   bool   // if true, tuple will be excluded from G{H,L}_TUPLE
+	pkg      string     // Go package name
+	version  string     // tag or commit ID
+	subdir   string     // GH_TUPLE subdir
+	group    string     // GH_TUPLE group
+	module   string     // module, if any
+	link_src *Tuple     // symlink source tuple, if any
+	link_tgt string     // symlink target, if any
+	source   Source     // tuple source (Github ot Gitlab)
+	account  string     // source account
+	project  string     // source project
+	hidden   bool       // if true, tuple will be excluded from G{H,L}_TUPLE
+	origin   *modOrigin // module origin from the module cache, if any
+
+	modPath      string    // Go module path, as required
+	modVersion   string    // Go module version, as listed in go.mod/modules.txt
+	modTime      time.Time // pseudo-version commit time
+	modBase      string    // pseudo-version base version
+	incompatible bool      // module version has "+incompatible" suffix
+	explicit     bool      // module is required explicitly, "## explicit" in modules.txt
+	goVersion    string    // go version the module declares, "## go 1.xx" in modules.txt
+	packages     []string  // vendored packages, as listed in modules.txt
+	modPackages  int       // number of packages in the module, 0 if unknown
+}
+
+// Package returns Go package name.
+func (t *Tuple) Package() string {
+	return t.pkg
+}
+
+// Version returns tag or commit ID the tuple is fetched at.
+func (t *Tuple) Version() string {
+	return t.version
+}
+
+// Source returns tuple source, nil if mirror is not known.
+func (t *Tuple) Source() Source {
+	return t.source
+}
+
+// Account returns source account.
+func (t *Tuple) Account() string {
+	return t.account
+}
+
+// Project returns source project.
+func (t *Tuple) Project() string {
+	return t.project
+}
+
+// Group returns tuple group name, WRKSRC_<group> is where the tuple is extracted.
+func (t *Tuple) Group() string {
+	return t.group
+}
+
+// Subdir returns path relative to WRKSRC the tuple is moved to, empty for linked tuples.
+func (t *Tuple) Subdir() string {
+	return t.subdirPath()
+}
+
+// Module returns module subdirectory in a multi-module repository, if any.
+func (t *Tuple) Module() string {
+	return t.module
+}
+
+// Hidden reports whether tuple is excluded from G{H,L}_TUPLE because it's
+// already extracted as a part of another tuple.
+func (t *Tuple) Hidden() bool {
+	return t.hidden
+}
+
+// LinkSource returns the tuple symlink points to, nil if tuple isn't linked.
+func (t *Tuple) LinkSource() *Tuple {
+	if !t.isLinked() {
+		return nil
+	}
+	if t.link_src != nil {
+		return t.link_src
+	}
+	return t
+}
+
+// LinkTarget returns symlink path relative to WRKSRC, empty if tuple isn't linked.
+func (t *Tuple) LinkTarget() string {
+	return t.link_tgt
+}
+
+// ModulePath returns Go module path, as required.
+func (t *Tuple) ModulePath() string {
+	return t.modPath
+}
+
+// ModuleVersion returns Go module version, as listed in go.mod or modules.txt.
+func (t *Tuple) ModuleVersion() string {
+	return t.modVersion
+}
+
+// ModuleTime returns commit time of pseudo-version, zero time for other versions.
+func (t *Tuple) ModuleTime() time.Time {
+	return t.modTime
+}
+
+// ModuleBaseVersion returns the version pseudo-version is based on, if any.
+func (t *Tuple) ModuleBaseVersion() string {
+	return t.modBase
+}
+
+// Incompatible reports whether module version has "+incompatible" suffix.
+func (t *Tuple) Incompatible() bool {
+	return t.incompatible
+}
+
+// Explicit reports whether module is required explicitly by the main module.
+func (t *Tuple) Explicit() bool {
+	return t.explicit
+}
+
+// GoVersion returns go version the module declares, if known.
+func (t *Tuple) GoVersion() string {
+	return t.goVersion
+}
+
+// Annotate records modules.txt "## explicit; go 1.xx" module annotations.
+func (t *Tuple) Annotate(explicit bool, goVersion string) {
+	t.explicit = explicit
+	t.goVersion = goVersion
 }
 
 var underscoreRe = regexp.MustCompile(`[^\w]+`)
@@ -127,12 +324,31 @@ func (t *Tuple) makeResolved(source Source, account, project, module string) {
 	t.group = strings.ToLower(group)
 }
 
+// makeProxied makes unresolved tuple fetched from the Go module proxy.
+func (t *Tuple) makeProxied(source ProxySource) {
+	t.source = source
+	t.version = t.modVersion
+
+	group := underscoreRe.ReplaceAllString(t.pkg, "_")
+	group = strings.Trim(group, "_")
+	t.group = strings.ToLower(group)
+}
+
 func (t *Tuple) isResolved() bool {
 	return t.source != nil
 }
 
-func (t *Tuple) Fix() error {
-	if config.Offline {
+func (t *Tuple) Fix(ctx context.Context, opts *Options) error {
+	if opts.preferTags() {
+		if err := t.fixPseudoVersion(ctx, opts); err != nil {
+			return err
+		}
+	}
+	if t.origin != nil && t.origin.Hash != "" {
+		t.fixFromOrigin(opts)
+		return nil
+	}
+	if opts.offline() {
 		return nil
 	}
 
@@ -142,56 +358,174 @@ func (t *Tuple) Fix() error {
 		// to check tags. Go seem to be able to magically translate tags like "v1.0.4" to the
 		// "api/v1.0.4", lets try to do the same.
 		if strings.HasPrefix(t.version, "v") && t.module != "" {
-			tag, err := apis.GithubLookupTag(t.account, t.project, t.module, t.version)
+			tag, err := opts.client().GithubLookupTag(ctx, t.account, t.project, t.module, t.version)
 			if err != nil {
 				return err
 			}
 			if t.version != tag {
-				debug.Printf("[Tuple.Fix] translated Github tag %q to %q\n", t.version, tag)
+				opts.Logf("[Tuple.Fix] translated Github tag %q to %q\n", t.version, tag)
 				t.version = tag
 			}
 		}
 		// If package is a module in a multi-module repo, adjust GH_SUBDIR
 		// NOTE: tag translation has to be done before this
 		if t.module != "" {
-			hasContentAtSuffix, err := apis.GithubHasContentsAtPath(t.account, t.project, t.module, t.version)
+			hasContentAtSuffix, err := opts.client().GithubHasContentsAtPath(ctx, t.account, t.project, t.module, t.version)
 			if err != nil {
 				return err
 			}
 			if hasContentAtSuffix {
 				// Trim suffix from GH_TUPLE subdir because repo already has contents and it'll
 				// be extracted at the correct path.
-				debug.Printf("[Tuple.Fix] trimmed module suffix %q from %q\n", t.module, t.subdir)
+				opts.Logf("[Tuple.Fix] trimmed module suffix %q from %q\n", t.module, t.subdir)
 				t.subdir = strings.TrimSuffix(t.subdir, "/"+t.module)
 			}
 		}
 		// Ports framework doesn't understand tags that have more than 2 path separators in it,
 		// replace by commit ID
 		if len(strings.Split(t.version, "/")) > 2 {
-			hash, err := apis.GithubGetCommit(t.account, t.project, t.version)
+			hash, err := opts.client().GithubGetCommit(ctx, t.account, t.project, t.version)
 			if err != nil {
 				return err
 			}
 			if len(hash) < 12 {
 				return errors.New("unexpectedly short Githib commit hash")
 			}
-			debug.Printf("[Tuple.Fix] translated Github tag %q to %q\n", t.version, hash[:12])
+			opts.Logf("[Tuple.Fix] translated Github tag %q to %q\n", t.version, hash[:12])
 			t.version = hash[:12]
 		}
 	case GitlabSource:
 		// Call Gitlab API to translate go.mod short commit IDs and tags
 		// to the full 40-character commit IDs as required by bsd.sites.mk
-		hash, err := apis.GitlabGetCommit(t.source.String(), t.account, t.project, t.version)
+		hash, err := opts.client().GitlabGetCommit(ctx, t.source.String(), t.account, t.project, t.version)
 		if err != nil {
 			return err
 		}
-		debug.Printf("[Tuple.Fix] translated Gitlab tag %q to %q\n", t.version, hash)
+		opts.Logf("[Tuple.Fix] translated Gitlab tag %q to %q\n", t.version, hash)
+		t.version = hash
+	case SourcehutSource:
+		// Translate tags and short commit IDs to the full commit IDs,
+		// so that the archive top-level directory name is predictable
+		hash, err := opts.client().SourcehutGetCommit(ctx, t.source.String(), t.account, t.project, t.version)
+		if err != nil {
+			return err
+		}
+		opts.Logf("[Tuple.Fix] translated SourceHut tag %q to %q\n", t.version, hash)
+		t.version = hash
+	case GiteaSource:
+		// Look up tags of modules in multi-module repos, as for Github above
+		if strings.HasPrefix(t.version, "v") && t.module != "" {
+			tag, err := opts.client().GiteaLookupTag(ctx, t.source.String(), t.account, t.project, t.module, t.version)
+			if err != nil {
+				return err
+			}
+			if t.version != tag {
+				opts.Logf("[Tuple.Fix] translated Gitea tag %q to %q\n", t.version, tag)
+				t.version = tag
+			}
+		}
+		// Translate tags and short commit IDs to the full commit IDs,
+		// tags with path separators don't make usable archive URLs
+		hash, err := opts.client().GiteaGetCommit(ctx, t.source.String(), t.account, t.project, t.version)
+		if err != nil {
+			return err
+		}
+		opts.Logf("[Tuple.Fix] translated Gitea tag %q to %q\n", t.version, hash)
+		t.version = hash
+	case BitbucketSource:
+		// Archive top-level directory name includes the commit ID,
+		// translate tags and short commit IDs to the full ones
+		hash, err := opts.client().BitbucketGetCommit(ctx, t.account, t.project, t.version)
+		if err != nil {
+			return err
+		}
+		opts.Logf("[Tuple.Fix] translated Bitbucket tag %q to %q\n", t.version, hash)
 		t.version = hash
 	}
 
 	return nil
 }
 
+// fixPseudoVersion replaces Github commit ID of a pseudo-version with a tag
+// pointing at the same commit. Tags are looked up in the module cache first,
+// then using Github API.
+func (t *Tuple) fixPseudoVersion(ctx context.Context, opts *Options) error {
+	if t.source != GH || !tagRx.MatchString(t.modVersion) {
+		return nil
+	}
+
+	tags := opts.modCacheTags(t.pkg, t.version)
+	if len(tags) == 0 && !opts.offline() {
+		var err error
+		tags, err = opts.client().GithubListTagsAt(ctx, t.account, t.project, t.version)
+		if err != nil {
+			return err
+		}
+	}
+
+	if tag := preferredTag(tags, t.module); tag != "" {
+		opts.Logf("[Tuple.Fix] translated Github commit %q to tag %q\n", t.version, tag)
+		t.version = tag
+	}
+	return nil
+}
+
+// preferredTag returns the best tag out of tags pointing at the same commit:
+// the shortest one with module prefix if module is in a subdir of a multi-module
+// repo, or the shortest one without any prefix.
+func preferredTag(tags []string, module string) string {
+	var prefixes []string
+	if module != "" {
+		prefixes = append(prefixes, module+"/")
+	}
+	prefixes = append(prefixes, "")
+
+	for _, prefix := range prefixes {
+		var res string
+		for _, tag := range tags {
+			if !strings.HasPrefix(tag, prefix) || strings.Contains(strings.TrimPrefix(tag, prefix), "/") {
+				continue
+			}
+			if res == "" || len(tag) < len(res) || len(tag) == len(res) && tag < res {
+				res = tag
+			}
+		}
+		if res != "" {
+			return res
+		}
+	}
+	return ""
+}
+
+// fixFromOrigin does what Fix does using module origin from the module cache
+// instead of API calls.
+func (t *Tuple) fixFromOrigin(opts *Options) {
+	o := t.origin
+
+	switch t.source.(type) {
+	case GithubSource:
+		// Origin ref is the actual tag, "api/v1.0.4" for "v1.0.4" of module in a
+		// multi-module repo
+		if tag := o.tag(); tag != "" && tag != t.version {
+			opts.Logf("[Tuple.Fix] translated Github tag %q to %q using module origin\n", t.version, tag)
+			t.version = tag
+		}
+		// Module is in the origin subdir, repo already has contents there
+		if t.module != "" && t.module == o.Subdir {
+			opts.Logf("[Tuple.Fix] trimmed module suffix %q from %q\n", t.module, t.subdir)
+			t.subdir = strings.TrimSuffix(t.subdir, "/"+t.module)
+		}
+		if len(strings.Split(t.version, "/")) > 2 && len(o.Hash) >= 12 {
+			opts.Logf("[Tuple.Fix] translated Github tag %q to %q using module origin\n", t.version, o.Hash[:12])
+			t.version = o.Hash[:12]
+		}
+	default:
+		// Full commit ID is what all other sources are translated to
+		opts.Logf("[Tuple.Fix] translated tag %q to %q using module origin\n", t.version, o.Hash)
+		t.version = o.Hash
+	}
+}
+
 func (t *Tuple) subdirPath() string {
 	if t.subdir == "" {
 		return ""
@@ -238,29 +572,146 @@ func (t *Tuple) String() string {
 	return res
 }
 
+type tupleJSON struct {
+	Pkg        string `json:"pkg"`
+	Version    string `json:"version"`
+	Source     string `json:"source,omitempty"`
+	Site       string `json:"site,omitempty"`
+	Account    string `json:"account"`
+	Project    string `json:"project"`
+	Group      string `json:"group"`
+	Subdir     string `json:"subdir,omitempty"`
+	Module     string `json:"module,omitempty"`
+	Hidden     bool   `json:"hidden,omitempty"`
+	LinkSource string `json:"link_source,omitempty"`
+	LinkTarget string `json:"link_target,omitempty"`
+
+	ModuleVersion string `json:"module_version,omitempty"`
+	Time          string `json:"time,omitempty"`
+	BaseVersion   string `json:"base_version,omitempty"`
+	Incompatible  bool   `json:"incompatible,omitempty"`
+	Explicit      bool   `json:"explicit,omitempty"`
+	GoVersion     string `json:"go_version,omitempty"`
+
+	Packages       []string `json:"packages,omitempty"`
+	ModulePackages int      `json:"module_packages,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t *Tuple) MarshalJSON() ([]byte, error) {
+	v := tupleJSON{
+		Pkg:     t.pkg,
+		Version: t.version,
+		Source:  sourceName(t.source),
+		Account: t.account,
+		Project: t.project,
+		Group:   t.group,
+		Subdir:  t.subdirPath(),
+		Module:  t.module,
+		Hidden:  t.hidden,
+
+		ModuleVersion: t.modVersion,
+		BaseVersion:   t.modBase,
+		Incompatible:  t.incompatible,
+		Explicit:      t.explicit,
+		GoVersion:     t.goVersion,
+
+		Packages:       t.packages,
+		ModulePackages: t.modPackages,
+	}
+	if !t.modTime.IsZero() {
+		v.Time = t.modTime.Format(time.RFC3339)
+	}
+	if t.source != nil {
+		v.Site = t.source.String()
+	}
+	if src := t.LinkSource(); src != nil {
+		v.LinkSource = src.group
+		v.LinkTarget = t.link_tgt
+	}
+	return json.Marshal(v)
+}
+
 func (t *Tuple) defaultSortKey() string {
 	return fmt.Sprintf("%s:%s:%s:%s:%s:%s:%s", t.source, t.account, t.project, t.version, t.module, t.group, t.link_tgt)
 }
 
 type Slice []*Tuple
 
-func (s Slice) Fix() error {
+func (s Slice) Fix(ctx context.Context, opts *Options) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if len(s) < 2 {
 		return nil
 	}
 
-	if err := fixGithubProjectsAndTags(s); err != nil {
+	if err := fixGithubProjectsAndTags(s, opts); err != nil {
 		return err
 	}
-	fixSubdirs(s)
-	fixGroups(s)
-	fixFsNotify(s)
+	fixSubdirs(s, opts)
+	fixAliases(s, opts)
+	fixArchiveDirs(s, opts)
+	fixGroups(s, opts)
+
+	return checkArchiveDirs(s)
+}
+
+// fixArchiveDirs takes care of tuples extracted into the same WRKDIR
+// subdirectory, Gitea and SourceHut archive names don't include the account.
+// Such tuples are fetched from the Go module proxy instead, unless other
+// tuples are extracted as a part of them.
+func fixArchiveDirs(s Slice, opts *Options) {
+	byDir := map[string]Slice{}
+	subdirs := map[string]int{}
+	for _, t := range s {
+		subdirs[t.subdir]++
+		ds, ok := t.source.(distfileSource)
+		if !ok || t.hidden || t.isLinked() {
+			continue
+		}
+		dir := ds.archiveDir(t)
+		byDir[dir] = append(byDir[dir], t)
+	}
 
+	proxy, _ := opts.goProxy()
+	for _, tt := range byDir {
+		if len(tt) < 2 {
+			continue
+		}
+		for _, t := range tt {
+			if _, ok := t.source.(ProxySource); ok || t.modVersion == "" || subdirs[t.subdir] > 1 {
+				continue
+			}
+			opts.Logf("[fixArchiveDirs] fetching %s@%s from the module proxy\n", t.pkg, t.modVersion)
+			t.makeProxied(proxy)
+			t.subdir = t.pkg
+			t.module = ""
+		}
+	}
+}
+
+// checkArchiveDirs makes sure tuples fetched as regular distfiles are
+// extracted into different WRKDIR subdirectories, otherwise they would
+// overwrite each other.
+func checkArchiveDirs(s Slice) error {
+	seen := map[string]*Tuple{}
+	for _, t := range s {
+		ds, ok := t.source.(distfileSource)
+		if !ok || t.hidden || t.isLinked() {
+			continue
+		}
+		dir := ds.archiveDir(t)
+		if prev, ok := seen[dir]; ok {
+			return fmt.Errorf("%s and %s are both extracted to ${WRKDIR}/%s", prev.pkg, t.pkg, dir)
+		}
+		seen[dir] = t
+	}
 	return nil
 }
 
 // fixGroups makes sure there are no duplicate group names.
-func fixGroups(s Slice) {
+func fixGroups(s Slice, opts *Options) {
 	var prevGroup string
 	suffix := 1
 
@@ -281,10 +732,10 @@ func fixGroups(s Slice) {
 		return key(i) < key(j)
 	})
 
-	if config.Debug {
-		debug.Print("[fixGroups] looking at slice:\n")
+	if opts.debug() {
+		opts.Logf("[fixGroups] looking at slice:\n")
 		for i := range s {
-			debug.Printf("[fixGroups]      %s\n", key(i))
+			opts.Logf("[fixGroups]      %s\n", key(i))
 		}
 	}
 
@@ -299,7 +750,7 @@ func fixGroups(s Slice) {
 		if t.group == prevGroup {
 			oldGroup := t.group
 			t.group = fmt.Sprintf("%s_%d", t.group, suffix)
-			debug.Printf("[fixGroups] deduped group %q as %q\n", oldGroup, t.group)
+			opts.Logf("[fixGroups] deduped group %q as %q\n", oldGroup, t.group)
 			suffix++
 		} else {
 			prevGroup = t.group
@@ -319,8 +770,8 @@ func (err DuplicateProjectAndTag) Error() string {
 // works, tuples sharing GH_PROJECT/GH_TAGNAME pair will be extracted into the same directory.
 // Try avoiding this mess by switching one of the conflicting tuple's GH_TAGNAME from git tag
 // to git commit ID.
-func fixGithubProjectsAndTags(s Slice) error {
-	if config.Offline {
+func fixGithubProjectsAndTags(s Slice, opts *Options) error {
+	if opts.offline() {
 		return nil
 	}
 
@@ -346,14 +797,14 @@ func fixGithubProjectsAndTags(s Slice) error {
 		if t.project == prevTuple.project && t.version == prevTuple.version && t.module == prevTuple.module {
 			// same Project and Version, different Account
 			if t.account != prevTuple.account {
-				// hash, err := apis.GithubGetCommit(t.account, t.project, t.version)
+				// hash, err := opts.client().GithubGetCommit(ctx, t.account, t.project, t.version)
 				// if err != nil {
 				// 	return DuplicateProjectAndTag(t.String())
 				// }
 				// if len(hash) < 12 {
 				// 	return errors.New("unexpectedly short Githib commit hash")
 				// }
-				// debug.Printf("[fixGithubProjectsAndTags] translated Github tag %q to %q\n", t.version, hash[:12])
+				// opts.Logf("[fixGithubProjectsAndTags] translated Github tag %q to %q\n", t.version, hash[:12])
 				// t.version = hash[:12]
 
 				// dont bother with replacing tag with commit hash, just link to prev tuple
@@ -398,7 +849,7 @@ func fixGithubProjectsAndTags(s Slice) error {
 }
 
 // fixSubdirs ensures that all subdirs are unique and makes symlinks as needed.
-func fixSubdirs(s Slice) {
+func fixSubdirs(s Slice, opts *Options) {
 	var maxSubdir, maxVersion, maxModule int
 	for _, t := range s {
 		if len(t.subdir) > maxSubdir {
@@ -419,10 +870,10 @@ func fixSubdirs(s Slice) {
 		return key(i) < key(j)
 	})
 
-	if config.Debug {
-		debug.Print("[fixSubdirs] looking at slice:\n")
+	if opts.debug() {
+		opts.Logf("[fixSubdirs] looking at slice:\n")
 		for i := range s {
-			debug.Printf("[fixSubdirs]     %s\n", key(i))
+			opts.Logf("[fixSubdirs]     %s\n", key(i))
 		}
 	}
 
@@ -440,10 +891,10 @@ func fixSubdirs(s Slice) {
 		currentSubdir, currentVersion = t.subdir, t.version
 		if prevSubdir == t.subdir {
 			if t.version != prevVersion {
-				debug.Printf("[fixSubdirs] linking %s/%s@%s (parent %s@%s)\n", t.pkg, t.module, t.version, prevSubdir, prevVersion)
+				opts.Logf("[fixSubdirs] linking %s/%s@%s (parent %s@%s)\n", t.pkg, t.module, t.version, prevSubdir, prevVersion)
 				t.makeLinked()
 			} else {
-				debug.Printf("[fixSubdirs] hiding %s/%s@%s (parent %s@%s)\n", t.pkg, t.module, t.version, prevSubdir, prevVersion)
+				opts.Logf("[fixSubdirs] hiding %s/%s@%s (parent %s@%s)\n", t.pkg, t.module, t.version, prevSubdir, prevVersion)
 				t.hidden = true
 			}
 		}
@@ -451,37 +902,6 @@ func fixSubdirs(s Slice) {
 	}
 }
 
-// fixFsNotify takes care of fsnotify annoying ability to appear under multiple different import paths.
-// github.com/fsnotify/fsnotify is the canonical package name.
-func fixFsNotify(s Slice) {
-	key := func(i int) string {
-		return fmt.Sprintf("%s:%s:%s:%s", s[i].account, s[i].project, s[i].version, s[i].group)
-	}
-	sort.Slice(s, func(i, j int) bool {
-		return key(i) < key(j)
-	})
-
-	const (
-		fsnotifyAccount = "fsnotify"
-		fsnotifyProject = "fsnotify"
-	)
-	var fsnotifyTuple *Tuple
-
-	for _, t := range s {
-		if t.account == fsnotifyAccount && t.project == fsnotifyProject && fsnotifyTuple == nil {
-			fsnotifyTuple = t
-			continue
-		}
-		if fsnotifyTuple != nil {
-			if t.version == fsnotifyTuple.version {
-				t.makeLinkedAs(fsnotifyTuple)
-				t.hidden = true
-				debug.Printf("[fixFsNotify] linking fnotify %s@%s => %s@%s\n", fsnotifyTuple.pkg, fsnotifyTuple.version, t.pkg, t.version)
-			}
-		}
-	}
-}
-
 // If tuple slice contains more than largeLimit entries, start tuple list on the new line for easier sorting/editing.
 // Otherwise omit the first line continuation for more compact representation.
 const largeLimit = 3
@@ -492,13 +912,15 @@ func (s Slice) String() string {
 		return s[i].defaultSortKey() < s[j].defaultSortKey()
 	})
 
-	var githubTuples, gitlabTuples Slice
+	var githubTuples, gitlabTuples, distfileTuples Slice
 	for _, t := range s {
 		switch t.source.(type) {
 		case GithubSource:
 			githubTuples = append(githubTuples, t)
 		case GitlabSource:
 			gitlabTuples = append(gitlabTuples, t)
+		case distfileSource:
+			distfileTuples = append(distfileTuples, t)
 		default:
 			panic("unknown source type")
 		}
@@ -528,10 +950,51 @@ func (s Slice) String() string {
 		}
 		lines = append(lines, buf.String())
 	}
+	if len(distfileTuples) > 0 {
+		lines = append(lines, distfileTuples.distfileVars())
+	}
 
 	return strings.Join(lines, "\n\n")
 }
 
+// MarshalJSON implements json.Marshaler.
+func (s Slice) MarshalJSON() ([]byte, error) {
+	// sort a copy, marshalling shouldn't reorder the slice
+	tt := append([]*Tuple(nil), s...)
+	sort.Slice(tt, func(i, j int) bool {
+		return tt[i].defaultSortKey() < tt[j].defaultSortKey()
+	})
+	return json.Marshal(tt)
+}
+
+// distfile returns the name of the tuple distfile, as derived by the ports framework.
+func (t *Tuple) distfile() string {
+	if _, ok := t.source.(ProxySource); ok {
+		return fmt.Sprintf("%s-%s%s", strings.ReplaceAll(t.pkg, "/", "-"), t.version, distfileSuffix(t.source))
+	}
+	return fmt.Sprintf("%s-%s-%s%s", t.account, t.project, strings.ReplaceAll(t.version, "/", "-"), distfileSuffix(t.source))
+}
+
+// Distfiles returns sorted distfile names of all tuples listed in G{H,L}_TUPLE.
+func (s Slice) Distfiles() []string {
+	var res []string
+
+	seen := map[string]bool{}
+	for _, t := range s {
+		if t.hidden {
+			continue
+		}
+		name := t.distfile()
+		if !seen[name] {
+			res = append(res, name)
+			seen[name] = true
+		}
+	}
+	sort.Strings(res)
+
+	return res
+}
+
 type Links []*Tuple
 
 // Links returns a slice of tuples that require symlinking.
@@ -558,9 +1021,13 @@ func (l Links) String() string {
 	if len(l) == 0 {
 		return ""
 	}
+	return postExtract(l.recipe(map[string]struct{}{}, nil))
+}
 
+// recipe returns post-extract recipe lines creating symlinks. Directories
+// already created are tracked in dirs.
+func (l Links) recipe(dirs map[string]struct{}, opts *Options) []string {
 	var lines []string
-	dirs := map[string]struct{}{}
 
 	for _, t := range l {
 		var b bytes.Buffer
@@ -569,11 +1036,11 @@ func (l Links) String() string {
 		var need_target_mkdir bool
 
 		if t.link_src != nil {
-			src = filepath.Join(fmt.Sprintf("${WRKSRC_%s}", t.link_src.group), t.link_src.module)
+			src = filepath.Join(t.link_src.wrksrc(), t.link_src.module)
 			// symlinking other package under different name, target dir is not guaranteed to exist
 			need_target_mkdir = true
 		} else {
-			src = filepath.Join(fmt.Sprintf("${WRKSRC_%s}", t.group), t.module)
+			src = filepath.Join(t.wrksrc(), t.module)
 			// symlinking module under another module, target dir already exists
 			need_target_mkdir = false
 		}
@@ -591,19 +1058,23 @@ func (l Links) String() string {
 
 		// symlinking over another module, rm -rf first
 		if t.module != "" {
-			debug.Printf("[Links.String] rm %s\n", tgt)
+			opts.Logf("[Links.recipe] rm %s\n", tgt)
 			b.WriteString(fmt.Sprintf("\t@${RM} -r %s\n", tgt))
 		}
 
-		debug.Printf("[Links.String] ln %s => %s\n", src, tgt)
+		opts.Logf("[Links.recipe] ln %s => %s\n", src, tgt)
 		b.WriteString(fmt.Sprintf("\t@${RLN} %s %s", src, tgt))
 
 		lines = append(lines, b.String())
 	}
 
+	return lines
+}
+
+func postExtract(recipe []string) string {
 	var b bytes.Buffer
 	b.WriteString("post-extract:\n")
-	b.WriteString(strings.Join(lines, "\n"))
+	b.WriteString(strings.Join(recipe, "\n"))
 
 	return b.String()
 }
@@ -1,15 +1,13 @@
 package tuple
 
 import (
+	"context"
+	"encoding/json"
 	"strings"
 	"testing"
-
-	"github.com/dmgk/modules2tuple/v2/config"
 )
 
-func init() {
-	config.Offline = true
-}
+var offlineOptions = &Options{Offline: true}
 
 func TestParseRegularSpec(t *testing.T) {
 	examples := [][]string{
@@ -82,7 +80,7 @@ func TestStringer(t *testing.T) {
 	}
 
 	for i, x := range examples {
-		tuple, err := Parse(x[0])
+		tuple, err := Parse(context.Background(), x[0], offlineOptions)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -101,7 +99,7 @@ func TestPackageReplace(t *testing.T) {
 	}
 
 	for i, x := range examples {
-		tuple, err := Parse(x[0])
+		tuple, err := Parse(context.Background(), x[0], offlineOptions)
 		if err != nil {
 			t.Fatalf("%T: %v", err, err)
 		}
@@ -111,3 +109,180 @@ func TestPackageReplace(t *testing.T) {
 		}
 	}
 }
+
+func TestProxyFallback(t *testing.T) {
+	examples := [][]string{
+		// spec, expected archive URL, expected archive dir
+		{"example.org/Account/project v1.2.3", "https://proxy.golang.org/example.org/!account/project/@v/v1.2.3.zip", "example.org/Account/project@v1.2.3"},
+		{"example.org/account/project v1.2.3-0.20190209023717-9147687966d9+incompatible", "https://proxy.golang.org/example.org/account/project/@v/v1.2.3-0.20190209023717-9147687966d9+incompatible.zip", "example.org/account/project@v1.2.3-0.20190209023717-9147687966d9+incompatible"},
+		{"github.com/pkg/errors v1.0.0 => example.org/fork/errors v1.0.1", "https://proxy.golang.org/example.org/fork/errors/@v/v1.0.1.zip", "example.org/fork/errors@v1.0.1"},
+	}
+
+	opts := &Options{Offline: true, GoProxy: DefaultGoProxy}
+	for i, x := range examples {
+		tuple, err := Parse(context.Background(), x[0], opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ds, ok := tuple.source.(distfileSource)
+		if !ok {
+			t.Fatalf("(%d) expected proxy source, got %T", i, tuple.source)
+		}
+		if s := ds.archiveURL(tuple); s != x[1] {
+			t.Errorf("(%d) expected archive URL to be %q, got %q", i, x[1], s)
+		}
+		if s := ds.archiveDir(tuple); s != x[2] {
+			t.Errorf("(%d) expected archive dir to be %q, got %q", i, x[2], s)
+		}
+	}
+
+	if _, err := Parse(context.Background(), "example.org/account/project v1.2.3", offlineOptions); err == nil {
+		t.Errorf("expected SourceError without proxy fallback")
+	}
+}
+
+func TestDistfile(t *testing.T) {
+	examples := [][]string{
+		// spec, expected distfile
+		{"github.com/pkg/errors v1.0.0", "pkg-errors-v1.0.0_GH0.tar.gz"},
+		{"github.com/pkg/errors v0.0.0-20181001143604-e0a95dfd547c", "pkg-errors-e0a95dfd547c_GH0.tar.gz"},
+		{"gitlab.com/gitlab-org/labkit v0.0.0-20190221122536-0c3fc7cdd57c", "gitlab-org-labkit-0c3fc7cdd57c_GL0.tar.gz"},
+		{"git.sr.ht/~sircmpwn/getopt v1.0.0", "sircmpwn-getopt-v1.0.0_SH0.tar.gz"},
+		{"codeberg.org/account/project v1.0.0", "account-project-v1.0.0_GT0.tar.gz"},
+		{"bitbucket.org/account/project v0.0.0-20190221122536-0c3fc7cdd57c", "account-project-0c3fc7cdd57c_BB0.tar.gz"},
+	}
+
+	for i, x := range examples {
+		tuple, err := Parse(context.Background(), x[0], offlineOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := tuple.distfile()
+		if s != x[1] {
+			t.Errorf("(%d) expected distfile to be %q, got %q", i, x[1], s)
+		}
+	}
+
+	tuple := &Tuple{source: ProxySource(""), pkg: "example.org/Account/project", version: "v1.0.0+incompatible"}
+	if s := tuple.distfile(); s != "example.org-Account-project-v1.0.0+incompatible_GP0.zip" {
+		t.Errorf("expected distfile to be %q, got %q", "example.org-Account-project-v1.0.0+incompatible_GP0.zip", s)
+	}
+
+	tuple = &Tuple{source: GH, account: "hashicorp", project: "vault", version: "api/v1.0.4"}
+	if s := tuple.distfile(); s != "hashicorp-vault-api-v1.0.4_GH0.tar.gz" {
+		t.Errorf("expected distfile to be %q, got %q", "hashicorp-vault-api-v1.0.4_GH0.tar.gz", s)
+	}
+}
+
+func TestParseDistfileSite(t *testing.T) {
+	examples := []string{
+		"git.sr.ht/~sircmpwn/getopt v1.0.0",
+		"codeberg.org/account/project v1.0.0",
+		"bitbucket.org/account/project v0.0.0-20190221122536-0c3fc7cdd57c",
+	}
+
+	for i, spec := range examples {
+		tuple, err := Parse(context.Background(), spec, offlineOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ds := tuple.source.(distfileSource)
+		entry := ds.archiveURL(tuple) + "?dummy=/:" + tuple.group
+
+		parsed, err := parseDistfileSite(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := parsed.source.(distfileSource).archiveURL(parsed) + "?dummy=/:" + parsed.group; s != entry {
+			t.Errorf("(%d) expected MASTER_SITES entry %q, got %q", i, entry, s)
+		}
+		if s := parsed.source.(distfileSource).archiveDir(parsed); s != ds.archiveDir(tuple) {
+			t.Errorf("(%d) expected archive dir %q, got %q", i, ds.archiveDir(tuple), s)
+		}
+	}
+
+	opts := &Options{Offline: true, GoProxy: DefaultGoProxy}
+	tuple, err := Parse(context.Background(), "example.org/Account/project v1.2.3", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := parseDistfileSite(tuple.source.(distfileSource).archiveURL(tuple) + "?dummy=/:" + tuple.group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.pkg != "example.org/Account/project" || parsed.version != "v1.2.3" {
+		t.Errorf("expected proxy module %q, got %s@%s", "example.org/Account/project@v1.2.3", parsed.pkg, parsed.version)
+	}
+
+	if _, err := parseDistfileSite("https://example.org/project.tar.gz"); err == nil {
+		t.Errorf("expected unexpected MASTER_SITES entry error")
+	}
+}
+
+func TestSliceFixArchiveDirs(t *testing.T) {
+	examples := []struct {
+		specs []string
+		sites string
+	}{
+		// same project of different accounts is fetched from the module proxy
+		{
+			[]string{"codeberg.org/foo/bar v1.0.0", "codeberg.org/baz/bar v1.0.0"},
+			`MASTER_SITES+=	https://proxy.golang.org/codeberg.org/baz/bar/@v/v1.0.0.zip?dummy=/:codeberg_org_baz_bar \
+		https://proxy.golang.org/codeberg.org/foo/bar/@v/v1.0.0.zip?dummy=/:codeberg_org_foo_bar`,
+		},
+		{
+			[]string{"git.sr.ht/~foo/bar v1.0.0", "git.sr.ht/~baz/bar v1.0.0"},
+			`MASTER_SITES+=	https://proxy.golang.org/git.sr.ht/~baz/bar/@v/v1.0.0.zip?dummy=/:git_sr_ht_baz_bar \
+		https://proxy.golang.org/git.sr.ht/~foo/bar/@v/v1.0.0.zip?dummy=/:git_sr_ht_foo_bar`,
+		},
+		{
+			[]string{"codeberg.org/foo/bar v1.0.0", "codeberg.org/foo/baz v1.0.0"},
+			`MASTER_SITES+=	https://codeberg.org/foo/bar/archive/v1.0.0.tar.gz?dummy=/:foo_bar \
+		https://codeberg.org/foo/baz/archive/v1.0.0.tar.gz?dummy=/:foo_baz`,
+		},
+	}
+
+	for i, x := range examples {
+		var s Slice
+		for _, spec := range x.specs {
+			tuple, err := Parse(context.Background(), spec, offlineOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s = append(s, tuple)
+		}
+		if err := s.Fix(context.Background(), offlineOptions); err != nil {
+			t.Errorf("(%d) expected no error, got %v", i, err)
+			continue
+		}
+		sites := strings.SplitN(s.distfileVars(), "\nDISTFILES", 2)[0]
+		if sites != x.sites {
+			t.Errorf("(%d) expected MASTER_SITES\n%s\n, got\n%s\n", i, x.sites, sites)
+		}
+	}
+}
+
+func TestSliceMarshalJSON(t *testing.T) {
+	var s Slice
+	for _, spec := range []string{"github.com/pkg/errors v0.9.1", "github.com/json-iterator/go v1.1.7"} {
+		tuple, err := Parse(context.Background(), spec, offlineOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s = append(s, tuple)
+	}
+	order := append(Slice(nil), s...)
+
+	out, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `[{"pkg":"github.com/json-iterator/go"`) {
+		t.Errorf("expected tuples to be sorted, got %s", out)
+	}
+	for i := range s {
+		if s[i] != order[i] {
+			t.Errorf("(%d) expected marshalling to keep slice order, got %s instead of %s", i, s[i], order[i])
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package tuple
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// v0.0.0-20181001143604-e0a95dfd547c
+// v1.2.4-0.20181001143604-e0a95dfd547c
+// v1.2.3-pre.0.20181001143604-e0a95dfd547c
+// v3.0.1-0.20190209023717-9147687966d9+incompatible
+var pseudoVersionRx = regexp.MustCompile(`\A(v\d+)\.(\d+)\.(\d+)-(?:([0-9A-Za-z\.-]+)\.)?(\d{14})-[0-9a-f]+(?:\+incompatible)?\z`)
+
+const pseudoVersionTimeLayout = "20060102150405"
+
+// setModVersion records Go module version and metadata derived from it.
+func (t *Tuple) setModVersion(version string) {
+	t.modVersion = version
+	t.incompatible = strings.HasSuffix(version, "+incompatible")
+
+	sm := pseudoVersionRx.FindStringSubmatch(version)
+	if sm == nil {
+		return
+	}
+	if tm, err := time.Parse(pseudoVersionTimeLayout, sm[5]); err == nil {
+		t.modTime = tm
+	}
+	t.modBase = pseudoVersionBase(sm[1], sm[2], sm[3], sm[4])
+}
+
+// pseudoVersionBase returns the version pseudo-version is based on, empty if
+// there's no base version:
+//
+//	vX.0.0-yyyymmddhhmmss-abcdef       -> ""
+//	vX.Y.Z-pre.0.yyyymmddhhmmss-abcdef -> vX.Y.Z-pre
+//	vX.Y.Z-0.yyyymmddhhmmss-abcdef     -> vX.Y.(Z-1)
+func pseudoVersionBase(major, minor, patch, pre string) string {
+	switch {
+	case pre == "0":
+		p, err := strconv.Atoi(patch)
+		if err != nil || p == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%s.%s.%d", major, minor, p-1)
+	case strings.HasSuffix(pre, ".0"):
+		return fmt.Sprintf("%s.%s.%s-%s", major, minor, patch, strings.TrimSuffix(pre, ".0"))
+	default:
+		return ""
+	}
+}
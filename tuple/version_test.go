@@ -0,0 +1,39 @@
+package tuple
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetModVersion(t *testing.T) {
+	examples := []struct {
+		version, base string
+		time          time.Time
+		incompatible  bool
+	}{
+		{"v1.2.3", "", time.Time{}, false},
+		{"v2.0.0+incompatible", "", time.Time{}, true},
+		{"v0.0.0-20181001143604-e0a95dfd547c", "", time.Date(2018, 10, 1, 14, 36, 4, 0, time.UTC), false},
+		{"v1.0.5-0.20200215224050-f6547fa8e820", "v1.0.4", time.Date(2020, 2, 15, 22, 40, 50, 0, time.UTC), false},
+		{"v1.2.3-pre.0.20181001143604-e0a95dfd547c", "v1.2.3-pre", time.Date(2018, 10, 1, 14, 36, 4, 0, time.UTC), false},
+		{"v3.0.1-0.20190209023717-9147687966d9+incompatible", "v3.0.0", time.Date(2019, 2, 9, 2, 37, 17, 0, time.UTC), true},
+		{"v0.0.1-zsh-completion-custom", "", time.Time{}, false},
+	}
+
+	for i, x := range examples {
+		tt := &Tuple{}
+		tt.setModVersion(x.version)
+		if tt.ModuleVersion() != x.version {
+			t.Errorf("(%d) expected version to be %q, got %q", i, x.version, tt.ModuleVersion())
+		}
+		if tt.ModuleBaseVersion() != x.base {
+			t.Errorf("(%d) expected base version to be %q, got %q", i, x.base, tt.ModuleBaseVersion())
+		}
+		if !tt.ModuleTime().Equal(x.time) {
+			t.Errorf("(%d) expected time to be %v, got %v", i, x.time, tt.ModuleTime())
+		}
+		if tt.Incompatible() != x.incompatible {
+			t.Errorf("(%d) expected incompatible to be %v, got %v", i, x.incompatible, tt.Incompatible())
+		}
+	}
+}